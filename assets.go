@@ -0,0 +1,80 @@
+package ditaconvert
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Asset is a file referenced from a topic, e.g. an image, video or download
+type Asset struct {
+	// Kind is "image", "video" or "download"
+	Kind string
+	// Path is the location of the file in the index file system
+	Path string
+	// Output is the location of the file relative to the output root
+	Output string
+}
+
+// ImageVariant is a downscaled copy of an image used in srcset
+type ImageVariant struct {
+	// Output is the location of the copy relative to the output root
+	Output string
+	Width  int
+}
+
+// isExternalURL checks whether href refers outside of the index file system
+func isExternalURL(href string) bool {
+	if i := strings.IndexRune(href, ':'); i >= 0 && strings.IndexRune(href[:i], '/') < 0 {
+		return true
+	}
+	return strings.HasPrefix(href, "//")
+}
+
+// AssetURL records the file referenced by href and returns the url of the
+// file relative to the output of the topic. External urls are returned as is.
+func (context *Context) AssetURL(kind, href string) string {
+	if href == "" || isExternalURL(href) {
+		return href
+	}
+
+	_, selector := SplitLink(href)
+	url := context.OutputURL(context.asset(kind, href).Output)
+	if selector != "" {
+		url += "#" + selector
+	}
+	return url
+}
+
+// asset records the local file referenced by href
+func (context *Context) asset(kind, href string) Asset {
+	name := path.Join(path.Dir(context.DecodingPath), trimLink(href))
+
+	asset := Asset{Kind: kind, Path: name, Output: name}
+	if context.AssetPath != nil {
+		asset.Output = context.AssetPath(asset)
+	}
+
+	for _, existing := range context.Assets {
+		if existing.Path == asset.Path {
+			return asset
+		}
+	}
+	context.Assets = append(context.Assets, asset)
+	return asset
+}
+
+// OutputURL returns the url of output, relative to the output root,
+// from the output of the topic
+func (context *Context) OutputURL(output string) string {
+	// the topic is output next to its source
+	base := context.DecodingPath
+	if context.Topic != nil {
+		base = context.Topic.Path
+	}
+	url, err := filepath.Rel(filepath.FromSlash(path.Dir(base)), filepath.FromSlash(output))
+	if err != nil {
+		return output
+	}
+	return filepath.ToSlash(url)
+}
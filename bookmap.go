@@ -0,0 +1,133 @@
+package ditaconvert
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+)
+
+// bookCounters is shared between all MapContexts of a single publication
+type bookCounters struct {
+	chapter  int
+	appendix int
+}
+
+// bookKinds are bookmap elements that are recorded as Entry.Kind
+var bookKinds = map[string]bool{
+	"chapter":  true,
+	"part":     true,
+	"appendix": true,
+	"notices":  true,
+
+	"abstract":     true,
+	"dedication":   true,
+	"preface":      true,
+	"colophon":     true,
+	"amendments":   true,
+	"bookabstract": true,
+}
+
+// bookListTitles contains default titles for generated booklists
+var bookListTitles = map[string]string{
+	"toc":           "Contents",
+	"indexlist":     "Index",
+	"glossarylist":  "Glossary",
+	"figurelist":    "List of figures",
+	"tablelist":     "List of tables",
+	"abbrevlist":    "Abbreviations",
+	"trademarklist": "Trademarks",
+	"bibliolist":    "Bibliography",
+}
+
+// isBookContainer checks whether node only groups other bookmap elements
+func isBookContainer(node *dita.MapNode) bool {
+	switch node.XMLName.Local {
+	case "bookmap", "frontmatter", "backmatter", "booklists":
+		return true
+	case "appendices":
+		return node.Href == ""
+	}
+	return false
+}
+
+// isBookListPlaceholder checks whether node is a booklist without authored content
+func isBookListPlaceholder(node *dita.MapNode) bool {
+	_, isList := bookListTitles[node.XMLName.Local]
+	return isList && node.Href == "" && len(node.Children) == 0
+}
+
+// NumberBookEntry assigns kind and number to a bookmap entry
+func (context MapContext) NumberBookEntry(entry *Entry, kind string) {
+	entry.Kind = kind
+	if context.counters == nil {
+		return
+	}
+
+	switch kind {
+	case "chapter":
+		context.counters.chapter++
+		entry.Number = strconv.Itoa(context.counters.chapter)
+	case "appendix":
+		context.counters.appendix++
+		entry.Number = letters(context.counters.appendix)
+	}
+}
+
+// BookSubmap numbers the entries of a submap referenced from a bookmap
+// element, e.g. <chapter format="ditamap">, as a single chapter.
+// Several top-level entries are nested under an entry with the title of
+// the submap.
+func (context MapContext) BookSubmap(node *dita.MapNode, entries []*Entry) []*Entry {
+	kind := node.XMLName.Local
+	if len(entries) == 1 {
+		context.NumberBookEntry(entries[0], kind)
+		return entries
+	}
+
+	entry := &Entry{
+		Title:    node.NavTitle,
+		CollType: context.CollType,
+		Linking:  context.Linking,
+		TOC:      context.TOC,
+		Metadata: context.Metadata,
+
+		Conditions: context.Conditions,
+		Children:   entries,
+	}
+	if meta := node.TopicMeta; meta != nil {
+		if navtitle := strings.TrimSpace(meta.NavTitle); navtitle != "" {
+			entry.Title = navtitle
+		}
+	}
+	if entry.Title == "" {
+		if m, ok := context.Maps[CanonicalPath(path.Join(context.Dir, node.Href))]; ok {
+			entry.Title = m.Title
+		}
+	}
+	context.NumberBookEntry(entry, kind)
+	return []*Entry{entry}
+}
+
+// mapTitle returns the title of a map, for bookmaps the main book title
+func mapTitle(node *dita.MapNode) string {
+	title := node.Title
+	if node.XMLName.Local == "bookmap" {
+		if main, _ := node.MainBookTitle.Text(); strings.TrimSpace(main) != "" {
+			title = main
+		}
+	}
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// letters converts 1, 2, ... 26, 27 to A, B, ... Z, AA
+func letters(n int) string {
+	s := ""
+	for n > 0 {
+		n--
+		s = string(rune('A'+n%26)) + s
+		n /= 26
+	}
+	return s
+}
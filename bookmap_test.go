@@ -0,0 +1,58 @@
+package ditaconvert
+
+import "testing"
+
+func TestBookmapChapterSubmaps(t *testing.T) {
+	index := NewIndex(VFS{
+		"book.ditamap": `<bookmap>
+			<booktitle><mainbooktitle>User <b>Guide</b></mainbooktitle></booktitle>
+			<chapter href="several.ditamap" format="ditamap"/>
+			<chapter href="single.ditamap" format="ditamap"/>
+			<chapter href="c.dita"/>
+			<appendix href="several.ditamap" format="ditamap" navtitle="Reference"/>
+		</bookmap>`,
+		"several.ditamap": `<map><title>Several topics</title>
+			<topicref href="a.dita"/>
+			<topicref href="b.dita"/>
+			<topicref href="c.dita"/>
+		</map>`,
+		"single.ditamap": `<map><title>Single topic</title>
+			<topicref href="b.dita"><topicref href="c.dita"/></topicref>
+		</map>`,
+		"a.dita": `<topic id="a"><title>A</title></topic>`,
+		"b.dita": `<topic id="b"><title>B</title></topic>`,
+		"c.dita": `<topic id="c"><title>C</title></topic>`,
+	})
+	index.LoadMap("book.ditamap")
+	for _, err := range index.Errors {
+		t.Errorf("loading map: %v", err)
+	}
+
+	if index.Nav.Title != "User Guide" {
+		t.Errorf("got book title %q, expected %q", index.Nav.Title, "User Guide")
+	}
+
+	tests := []struct {
+		kind     string
+		number   string
+		title    string
+		children int
+	}{
+		{"chapter", "1", "Several topics", 3},
+		{"chapter", "2", "B", 1},
+		{"chapter", "3", "C", 0},
+		{"appendix", "A", "Reference", 3},
+	}
+	chapters := index.Nav.Children
+	if len(chapters) != len(tests) {
+		t.Fatalf("got %d top-level entries, expected %d", len(chapters), len(tests))
+	}
+	for i, test := range tests {
+		entry := chapters[i]
+		if entry.Kind != test.kind || entry.Number != test.number || entry.Title != test.title || len(entry.Children) != test.children {
+			t.Errorf("%d: got %s %q %q with %d children, expected %s %q %q with %d children", i,
+				entry.Kind, entry.Number, entry.Title, len(entry.Children),
+				test.kind, test.number, test.title, test.children)
+		}
+	}
+}
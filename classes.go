@@ -0,0 +1,271 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// IsDITAClass checks whether value is a DITA class attribute value,
+// e.g. "- topic/keyword pr-d/apiname "
+func IsDITAClass(value string) bool {
+	return strings.HasPrefix(value, "- ") || strings.HasPrefix(value, "+ ")
+}
+
+// ClassAncestry returns element names from the DITA class value,
+// starting from the most specialized, e.g. [apiname keyword]
+func ClassAncestry(value string) []string {
+	if !IsDITAClass(value) {
+		return nil
+	}
+
+	var names []string
+	tokens := strings.Fields(value[2:])
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := tokens[i]
+		if p := strings.IndexByte(token, '/'); p >= 0 {
+			token = token[p+1:]
+		}
+		if token != "" {
+			names = append(names, token)
+		}
+	}
+	return names
+}
+
+// IsSpecializationOf checks whether start is base or its specialization
+func IsSpecializationOf(start *xml.StartElement, base string) bool {
+	if start.Name.Local == base {
+		return true
+	}
+
+	class := getAttr(start, "class")
+	if !IsDITAClass(class) {
+		class = DefaultClass[start.Name.Local]
+	}
+	for _, ancestor := range ClassAncestry(class) {
+		if ancestor == base {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRule checks whether any rule is defined for the element name,
+// including rules that only modify attributes
+func (rules *Rules) HasRule(name string) bool {
+	if rules.hasConversionRule(name) {
+		return true
+	}
+	if _, ok := rules.AddAttributes[name]; ok {
+		return true
+	}
+	if _, ok := rules.DropAttributes[name]; ok {
+		return true
+	}
+	return false
+}
+
+// hasConversionRule checks whether name has a rule other than
+// modifying attributes
+func (rules *Rules) hasConversionRule(name string) bool {
+	if _, ok := rules.Custom[name]; ok {
+		return true
+	}
+	if _, ok := rules.Rename[name]; ok {
+		return true
+	}
+	if _, ok := rules.Skip[name]; ok {
+		return true
+	}
+	if _, ok := rules.Unwrap[name]; ok {
+		return true
+	}
+	if _, ok := rules.Wrap[name]; ok {
+		return true
+	}
+	return false
+}
+
+// RuleName returns the name used to look up rules for start,
+// specialized elements without rules use the closest ancestor in the
+// class attribute or in the DTD default class. Attribute rules do not
+// stop the lookup, they are applied in addition to the ancestor rules.
+func (rules *Rules) RuleName(start *xml.StartElement) string {
+	name := start.Name.Local
+	if rules.hasConversionRule(name) {
+		return name
+	}
+
+	class := getAttr(start, "class")
+	if !IsDITAClass(class) {
+		class = DefaultClass[name]
+	}
+
+	for _, ancestor := range ClassAncestry(class) {
+		if rules.hasConversionRule(ancestor) || htmlIdentical[ancestor] {
+			return ancestor
+		}
+	}
+	return name
+}
+
+// htmlIdentical contains base DITA elements which are output as is
+var htmlIdentical = map[string]bool{
+	"p":   true,
+	"div": true,
+	"ul":  true,
+	"ol":  true,
+	"li":  true,
+	"dl":  true,
+	"dt":  true,
+	"dd":  true,
+	"pre": true,
+	"q":   true,
+	"sup": true,
+	"sub": true,
+	"u":   true,
+}
+
+// DefaultClass contains DTD default class values for specialized elements
+var DefaultClass = map[string]string{
+	// highlighting domain
+	"b":            "+ topic/ph hi-d/b ",
+	"i":            "+ topic/ph hi-d/i ",
+	"u":            "+ topic/ph hi-d/u ",
+	"tt":           "+ topic/ph hi-d/tt ",
+	"sup":          "+ topic/ph hi-d/sup ",
+	"sub":          "+ topic/ph hi-d/sub ",
+	"line-through": "+ topic/ph hi-d/line-through ",
+	"overline":     "+ topic/ph hi-d/overline ",
+
+	// programming domain
+	"codeph":        "+ topic/ph pr-d/codeph ",
+	"codeblock":     "+ topic/pre pr-d/codeblock ",
+	"coderef":       "+ topic/xref pr-d/coderef ",
+	"apiname":       "+ topic/keyword pr-d/apiname ",
+	"option":        "+ topic/keyword pr-d/option ",
+	"parmname":      "+ topic/keyword pr-d/parmname ",
+	"kwd":           "+ topic/keyword pr-d/kwd ",
+	"var":           "+ topic/ph pr-d/var ",
+	"synph":         "+ topic/ph pr-d/synph ",
+	"oper":          "+ topic/ph pr-d/oper ",
+	"delim":         "+ topic/ph pr-d/delim ",
+	"sep":           "+ topic/ph pr-d/sep ",
+	"repsep":        "+ topic/ph pr-d/repsep ",
+	"parml":         "+ topic/dl pr-d/parml ",
+	"plentry":       "+ topic/dlentry pr-d/plentry ",
+	"pt":            "+ topic/dt pr-d/pt ",
+	"pd":            "+ topic/dd pr-d/pd ",
+	"syntaxdiagram": "+ topic/fig pr-d/syntaxdiagram ",
+	"synblk":        "+ topic/figgroup pr-d/synblk ",
+	"groupseq":      "+ topic/figgroup pr-d/groupseq ",
+	"groupchoice":   "+ topic/figgroup pr-d/groupchoice ",
+	"groupcomp":     "+ topic/figgroup pr-d/groupcomp ",
+	"fragment":      "+ topic/figgroup pr-d/fragment ",
+	"fragref":       "+ topic/xref pr-d/fragref ",
+	"synnote":       "+ topic/fn pr-d/synnote ",
+	"synnoteref":    "+ topic/xref pr-d/synnoteref ",
+
+	// software domain
+	"msgph":        "+ topic/ph sw-d/msgph ",
+	"msgblock":     "+ topic/pre sw-d/msgblock ",
+	"msgnum":       "+ topic/keyword sw-d/msgnum ",
+	"cmdname":      "+ topic/keyword sw-d/cmdname ",
+	"varname":      "+ topic/keyword sw-d/varname ",
+	"filepath":     "+ topic/ph sw-d/filepath ",
+	"userinput":    "+ topic/ph sw-d/userinput ",
+	"systemoutput": "+ topic/ph sw-d/systemoutput ",
+
+	// user interface domain
+	"uicontrol":   "+ topic/ph ui-d/uicontrol ",
+	"wintitle":    "+ topic/keyword ui-d/wintitle ",
+	"menucascade": "+ topic/ph ui-d/menucascade ",
+	"shortcut":    "+ topic/keyword ui-d/shortcut ",
+	"screen":      "+ topic/pre ui-d/screen ",
+
+	// markup and xml domains
+	"markupname":      "+ topic/keyword markup-d/markupname ",
+	"xmlelement":      "+ topic/keyword markup-d/markupname xml-d/xmlelement ",
+	"xmlatt":          "+ topic/keyword markup-d/markupname xml-d/xmlatt ",
+	"textentity":      "+ topic/keyword markup-d/markupname xml-d/textentity ",
+	"parameterentity": "+ topic/keyword markup-d/markupname xml-d/parameterentity ",
+	"numcharref":      "+ topic/keyword markup-d/markupname xml-d/numcharref ",
+	"xmlnsname":       "+ topic/keyword markup-d/markupname xml-d/xmlnsname ",
+	"xmlpi":           "+ topic/keyword markup-d/markupname xml-d/xmlpi ",
+
+	// hazard statement domain
+	"hazardstatement": "+ topic/note hazard-d/hazardstatement ",
+	"messagepanel":    "+ topic/ul hazard-d/messagepanel ",
+	"typeofhazard":    "+ topic/li hazard-d/typeofhazard ",
+	"consequence":     "+ topic/li hazard-d/consequence ",
+	"howtoavoid":      "+ topic/li hazard-d/howtoavoid ",
+	"hazardsymbol":    "+ topic/image hazard-d/hazardsymbol ",
+
+	// utilities domain
+	"imagemap": "+ topic/fig ut-d/imagemap ",
+	"area":     "+ topic/figgroup ut-d/area ",
+	"shape":    "+ topic/keyword ut-d/shape ",
+	"coords":   "+ topic/ph ut-d/coords ",
+
+	// abbreviated form and glossary
+	"abbreviated-form": "+ topic/term abbrev-d/abbreviated-form ",
+	"glossterm":        "- topic/title concept/title glossentry/glossterm ",
+	"glossdef":         "- topic/abstract concept/abstract glossentry/glossdef ",
+
+	// topic types
+	"concept":         "- topic/topic concept/concept ",
+	"task":            "- topic/topic task/task ",
+	"reference":       "- topic/topic reference/reference ",
+	"troubleshooting": "- topic/topic troubleshooting/troubleshooting ",
+	"glossentry":      "- topic/topic concept/concept glossentry/glossentry ",
+	"glossgroup":      "- topic/topic concept/concept glossgroup/glossgroup ",
+
+	// task
+	"steps":               "- topic/ol task/steps ",
+	"steps-unordered":     "- topic/ul task/steps-unordered ",
+	"steps-informal":      "- topic/section task/steps-informal ",
+	"step":                "- topic/li task/step ",
+	"stepsection":         "- topic/li task/stepsection ",
+	"cmd":                 "- topic/ph task/cmd ",
+	"info":                "- topic/itemgroup task/info ",
+	"substeps":            "- topic/ol task/substeps ",
+	"substep":             "- topic/li task/substep ",
+	"stepxmp":             "- topic/itemgroup task/stepxmp ",
+	"stepresult":          "- topic/itemgroup task/stepresult ",
+	"steptroubleshooting": "- topic/itemgroup task/steptroubleshooting ",
+	"tutorialinfo":        "- topic/itemgroup task/tutorialinfo ",
+	"choices":             "- topic/ul task/choices ",
+	"choice":              "- topic/li task/choice ",
+	"prereq":              "- topic/section task/prereq ",
+	"context":             "- topic/section task/context ",
+	"result":              "- topic/section task/result ",
+	"tasktroubleshooting": "- topic/section task/tasktroubleshooting ",
+	"postreq":             "- topic/section task/postreq ",
+
+	"chhead":      "- topic/sthead task/chhead ",
+	"chrow":       "- topic/strow task/chrow ",
+	"choicetable": "- topic/simpletable task/choicetable ",
+	"choptionhd":  "- topic/stentry task/choptionhd ",
+	"chdeschd":    "- topic/stentry task/chdeschd ",
+	"choption":    "- topic/stentry task/choption ",
+	"chdesc":      "- topic/stentry task/chdesc ",
+
+	// reference
+	"refsyn":      "- topic/section reference/refsyn ",
+	"properties":  "- topic/simpletable reference/properties ",
+	"prophead":    "- topic/sthead reference/prophead ",
+	"proptypehd":  "- topic/stentry reference/proptypehd ",
+	"propvaluehd": "- topic/stentry reference/propvaluehd ",
+	"propdeschd":  "- topic/stentry reference/propdeschd ",
+	"property":    "- topic/strow reference/property ",
+	"proptype":    "- topic/stentry reference/proptype ",
+	"propvalue":   "- topic/stentry reference/propvalue ",
+	"propdesc":    "- topic/stentry reference/propdesc ",
+
+	// troubleshooting
+	"condition":        "- topic/section troubleshooting/condition ",
+	"troubleSolution":  "- topic/bodydiv troubleshooting/troubleSolution ",
+	"cause":            "- topic/section troubleshooting/cause ",
+	"remedy":           "- topic/section troubleshooting/remedy ",
+	"responsibleParty": "- topic/p troubleshooting/responsibleParty ",
+}
@@ -0,0 +1,56 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpecializedCustomElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notwant []string
+	}{
+		{
+			name: "section",
+			body: `<mycontext class="- topic/section task/context my-d/mycontext ">Text</mycontext>`,
+			want: []string{`class="section context"`, TaskLabels["context"]},
+		},
+		{
+			name:    "steps-unordered",
+			body:    `<mysteps class="- topic/ul task/steps-unordered my-d/mysteps "><step><cmd>Do</cmd></step></mysteps>`,
+			want:    []string{`<ul class="steps-unordered">`},
+			notwant: []string{`<ol`},
+		},
+		{
+			name: "properties",
+			body: `<myprops class="- topic/simpletable reference/properties my-d/myprops "><property><proptype>T</proptype></property></myprops>`,
+			want: []string{`<table class="properties">`, `<td class="proptype">T</td>`},
+		},
+		{
+			name: "step",
+			body: `<steps><mystep class="- topic/li task/step my-d/mystep " importance="optional"><cmd>Do</cmd></mystep></steps>`,
+			want: []string{`<li class="step">(Optional) `},
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"t.dita":    `<task id="t"><title>T</title><taskbody>` + test.body + `</taskbody></task>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.name, want, output)
+			}
+		}
+		for _, notwant := range test.notwant {
+			if strings.Contains(output, notwant) {
+				t.Errorf("%s: contains %q:\n%s", test.name, notwant, output)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Conditions contains cascading metadata attributes, attribute name --> value
+type Conditions map[string]string
+
+// attributes whose values are merged with the parent values
+var mergedAttributes = map[string]bool{
+	"audience":       true,
+	"platform":       true,
+	"product":        true,
+	"props":          true,
+	"otherprops":     true,
+	"deliveryTarget": true,
+}
+
+// attributes whose values override the parent values
+var overriddenAttributes = map[string]bool{
+	"print":      true,
+	"rev":        true,
+	"importance": true,
+	"xml:lang":   true,
+}
+
+// namespace used by encoding/xml for the xml: prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+func conditionName(name xml.Name) string {
+	if name.Space == xmlNamespace || name.Space == "xml" {
+		return "xml:" + name.Local
+	}
+	return name.Local
+}
+
+// ConditionsFromAttrs extracts cascading attributes
+func ConditionsFromAttrs(attrs []xml.Attr) Conditions {
+	var conditions Conditions
+	for _, attr := range attrs {
+		name := conditionName(attr.Name)
+		if !mergedAttributes[name] && !overriddenAttributes[name] {
+			continue
+		}
+
+		value := strings.TrimSpace(attr.Value)
+		if value == "" {
+			continue
+		}
+
+		if conditions == nil {
+			conditions = make(Conditions)
+		}
+		conditions[name] = value
+	}
+	return conditions
+}
+
+// Cascade returns the conditions for a child element, when merge is false
+// the child values replace the parent values.
+func (parent Conditions) Cascade(child Conditions, merge bool) Conditions {
+	if len(child) == 0 {
+		return parent
+	}
+	if len(parent) == 0 {
+		return child
+	}
+
+	result := make(Conditions, len(parent)+len(child))
+	for name, value := range parent {
+		result[name] = value
+	}
+
+	for name, value := range child {
+		if merge && mergedAttributes[name] && result[name] != "" {
+			tokens := appendUnique(strings.Fields(result[name]), strings.Fields(value)...)
+			result[name] = strings.Join(tokens, " ")
+		} else {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// Shared returns the conditions that apply through both conditions and other,
+// i.e. attributes set in both, merged attributes with the values of both.
+func (conditions Conditions) Shared(other Conditions) Conditions {
+	var shared Conditions
+	for name, value := range conditions {
+		othervalue, ok := other[name]
+		switch {
+		case !ok:
+			continue
+		case value == othervalue:
+		case mergedAttributes[name]:
+			tokens := appendUnique(strings.Fields(value), strings.Fields(othervalue)...)
+			value = strings.Join(tokens, " ")
+		default:
+			continue
+		}
+
+		if shared == nil {
+			shared = make(Conditions)
+		}
+		shared[name] = value
+	}
+	return shared
+}
+
+// Values returns all values for the attribute
+func (conditions Conditions) Values(name string) []string {
+	return strings.Fields(conditions[name])
+}
+
+// IsWebAudience checks whether the conditions allow output on the web
+func (conditions Conditions) IsWebAudience() bool {
+	return isWebAudience(conditions["audience"], conditions["print"], conditions["deliveryTarget"])
+}
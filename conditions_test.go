@@ -0,0 +1,88 @@
+package ditaconvert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTopicReferenceConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		refs      string
+		condition Conditions
+	}{
+		{
+			name:      "single reference",
+			refs:      `<topicref href="t.dita" product="new" platform="win"/>`,
+			condition: Conditions{"product": "new", "platform": "win"},
+		},
+		{
+			name: "merged values",
+			refs: `<topicref href="t.dita" product="new"/>
+				<topicref href="t.dita" product="next"/>`,
+			condition: Conditions{"product": "new next"},
+		},
+		{
+			name: "unconditional reference",
+			refs: `<topicref href="t.dita" product="new"/>
+				<topicref href="t.dita"/>`,
+			condition: nil,
+		},
+		{
+			name: "different attributes",
+			refs: `<topicref href="t.dita" product="new" rev="2"/>
+				<topicref href="t.dita" platform="win" rev="3"/>`,
+			condition: nil,
+		},
+		{
+			name: "shared attribute",
+			refs: `<topicgroup platform="win">
+					<topicref href="t.dita" product="new"/>
+					<topicref href="t.dita" product="next"/>
+				</topicgroup>`,
+			condition: Conditions{"platform": "win", "product": "new next"},
+		},
+	}
+
+	for _, test := range tests {
+		index := NewIndex(VFS{
+			"m.ditamap": `<map>` + test.refs + `</map>`,
+			"t.dita":    `<topic id="t"><title>T</title><body><p>Text</p></body></topic>`,
+		})
+		index.LoadMap("m.ditamap")
+		for _, err := range index.Errors {
+			t.Errorf("%s: %v", test.name, err)
+		}
+
+		topic := index.Topics["t.dita"]
+		if !reflect.DeepEqual(topic.Conditions, test.condition) {
+			t.Errorf("%s: got %v, expected %v", test.name, topic.Conditions, test.condition)
+		}
+	}
+}
+
+func TestTopicElementConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		topic    string
+		included bool
+	}{
+		{"included topic", `<topic id="t" product="new"><title>T</title><body><p>Text</p></body></topic>`, true},
+		{"excluded topic", `<topic id="t" product="old"><title>T</title><body><p>Text</p></body></topic>`, false},
+		{"excluded body", `<topic id="t"><title>T</title><body product="old"><p>Text</p></body></topic>`, false},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap":      `<map><topicref href="t.dita"/></map>`,
+			"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+			"t.dita":         test.topic,
+		}, "t.dita")
+
+		output := context.Output.String()
+		if included := strings.Contains(output, "Text"); included != test.included {
+			t.Errorf("%s: content included %v, expected %v:\n%s", test.name, included, test.included, output)
+		}
+	}
+}
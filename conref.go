@@ -34,9 +34,9 @@ func (context *Context) ResolveKeyRef(keyref string) (abspath, itempath string)
 	return abspath, items[1]
 }
 
-func (context *Context) HandleConref(dec *xml.Decoder, start xml.StartElement) error {
-	dec.Skip()
-
+// openConref resolves the conref or conkeyref of start, subdec is positioned
+// after the first referenced element and endingid is the id of the last one
+func (context *Context) openConref(start xml.StartElement) (subdec *xml.Decoder, subfirst xml.StartElement, endingid string, err error) {
 	conref, conkeyref, conrefend := getAttr(&start, "conref"), getAttr(&start, "conkeyref"), getAttr(&start, "conrefend")
 	keyfile, keypath := context.ResolveKeyRef(conkeyref)
 
@@ -56,7 +56,7 @@ func (context *Context) HandleConref(dec *xml.Decoder, start xml.StartElement) e
 	// conref is missing, try to use conkeyref instead
 	if startfile == "" && keyfile != "" {
 		if startpath != "" || endpath != "" {
-			return errors.New("invalid conkeyref setup")
+			return nil, subfirst, "", errors.New("invalid conkeyref setup")
 		}
 		startfile, startpath = keyfile, keypath
 	}
@@ -73,37 +73,49 @@ func (context *Context) HandleConref(dec *xml.Decoder, start xml.StartElement) e
 
 	// sanity check
 	if startfile != endfile {
-		return errors.New("conref and conrefend are in different files: " + startfile + " --> " + endfile)
+		return nil, subfirst, "", errors.New("conref and conrefend are in different files: " + startfile + " --> " + endfile)
 	}
 
 	if !SameRootElement(startpath, endpath) {
-		return errors.New("conref and conrefend have different root elements: " + conref + " --> " + conrefend)
+		return nil, subfirst, "", errors.New("conref and conrefend have different root elements: " + conref + " --> " + conrefend)
 	}
 	if startpath == "" || endpath == "" {
-		return errors.New("invalid conref path: " + conref + " --> " + conrefend)
+		return nil, subfirst, "", errors.New("invalid conref path: " + conref + " --> " + conrefend)
 	}
 
-	previousPath := context.DecodingPath
-	defer func() {
-		context.DecodingPath = previousPath
-	}()
-
 	data, _, err := context.Index.ReadFile(startfile)
 	if err != nil {
-		return fmt.Errorf("problem opening %v: %v", startfile, err)
+		return nil, subfirst, "", fmt.Errorf("problem opening %v: %v", startfile, err)
 	}
 
-	subdec := xml.NewDecoder(bytes.NewReader(data))
-	subfirst, err := WalkNodePath(subdec, startpath)
+	subdec = xml.NewDecoder(bytes.NewReader(data))
+	subfirst, err = WalkNodePath(subdec, startpath)
 	if err != nil {
 		if err == io.EOF {
-			return errors.New("did not find conref: " + conref)
+			return nil, subfirst, "", errors.New("did not find conref: " + conref)
 		}
+		return nil, subfirst, "", err
+	}
+	return subdec, subfirst, path.Base(endpath), nil
+}
+
+func (context *Context) HandleConref(dec *xml.Decoder, start xml.StartElement) error {
+	dec.Skip()
+
+	subdec, subfirst, endingid, err := context.openConref(start)
+	if err != nil {
 		return err
 	}
 
+	previousPath := context.DecodingPath
+	context.conrefDepth++
+	defer func() {
+		context.DecodingPath = previousPath
+		context.conrefDepth--
+	}()
+
+	conrefend := getAttr(&start, "conrefend")
 	var subtoken xml.Token = subfirst
-	endingid := path.Base(endpath)
 	for {
 		err := context.Handle(subdec, subtoken)
 		if err != nil {
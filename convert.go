@@ -2,28 +2,43 @@ package ditaconvert
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/raintreeinc/ditaconvert/html"
+	"github.com/raintreeinc/ditaconvert/imagemap"
 )
 
 type TokenProcessor func(*Context, *xml.Decoder, xml.StartElement) error
 
-type Renaming struct{ Name, AddClass string }
+type Renaming struct {
+	Name     string `json:"name"`
+	AddClass string `json:"class,omitempty"`
+}
 
 type Rules struct {
-	CustomResolveLink func(url string) (href, title, synopsis string, internal bool)
-
-	Rename map[string]Renaming
-	Skip   map[string]bool
-	Unwrap map[string]bool
-	Custom map[string]TokenProcessor
+	CustomResolveLink func(url string) (href, title, synopsis string, internal bool) `json:"-"`
+
+	Rename map[string]Renaming       `json:"rename,omitempty"`
+	Skip   map[string]bool           `json:"skip,omitempty"`
+	Unwrap map[string]bool           `json:"unwrap,omitempty"`
+	Custom map[string]TokenProcessor `json:"-"`
+
+	// Unknown specifies how to handle elements without any rules
+	Unknown UnknownPolicy `json:"unknown,omitempty"`
+
+	// Wrap encloses the element in an additional element
+	Wrap map[string]Renaming `json:"wrap,omitempty"`
+	// AddAttributes and DropAttributes modify attributes of the element,
+	// "*" can be used to match all elements
+	AddAttributes  map[string]map[string]string `json:"add-attributes,omitempty"`
+	DropAttributes map[string][]string          `json:"drop-attributes,omitempty"`
 }
 
 type Context struct {
@@ -35,6 +50,48 @@ type Context struct {
 
 	DecodingPath string
 
+	// HeadingLevel is the level of the topic title,
+	// section titles are output one level below
+	HeadingLevel int
+	// number of enclosing section-like elements
+	sectionDepth int
+	// number of enclosing conrefs, included content is not numbered
+	conrefDepth int
+	// ids of the enclosing elements, used for reporting locations
+	elementIDs []string
+
+	// conditional processing attributes inherited from the map
+	// and the ancestors of the current element
+	Conditions Conditions
+
+	// IndexTerms contains all indexterms found in the prolog and the body
+	IndexTerms []string
+
+	// generated anchor counts, prefix --> count
+	anchors map[string]int
+
+	// glossentry keys already used in this topic
+	glossaryUsed map[string]bool
+
+	// whether the imagemap style has been output in this topic
+	imagemapStyled bool
+
+	// numbered elements found in this topic, kind --> count
+	labelCount map[string]int
+
+	// Assets contains the images, videos and downloads referenced by the topic
+	Assets []Asset
+	// InlineImageLimit is the size in bytes up to which images are
+	// inlined as data urls, 0 disables inlining
+	InlineImageLimit int64
+
+	// AssetPath returns the output location of asset relative to the
+	// output root, nil keeps assets at their source location
+	AssetPath func(asset Asset) string
+	// ImageVariants returns downscaled copies of image with the intrinsic
+	// width and height for srcset, nil disables srcset
+	ImageVariants func(image Asset, width, height int) []ImageVariant
+
 	Errors []error
 }
 
@@ -48,6 +105,8 @@ func NewConversion(index *Index, topic *Topic) *Context {
 		Rules:   NewDefaultRules(),
 
 		DecodingPath: topic.Path,
+		HeadingLevel: DefaultHeadingLevel,
+		Conditions:   topic.Conditions,
 	}
 }
 
@@ -64,13 +123,28 @@ func (context *Context) errorf(format string, args ...interface{}) {
 	context.Errors = append(context.Errors, err)
 }
 
+// NewAnchor returns a generated id unique within the topic, e.g. "prefix-3"
+func (context *Context) NewAnchor(prefix string) string {
+	if context.anchors == nil {
+		context.anchors = make(map[string]int)
+	}
+	context.anchors[prefix]++
+	return prefix + "-" + strconv.Itoa(context.anchors[prefix])
+}
+
 func (context *Context) Run() error {
 	topic := context.Topic.Original
 	if topic == nil {
 		return fmt.Errorf("no associated topic")
 	}
 
+	// conditions on the topic element apply to the whole topic
+	context.Conditions, _ = context.ElementConditions(context.Conditions, topic.XMLName.Local, topic.Attrs)
+
 	body := ""
+	if topic.XMLName.Local == "glossentry" {
+		body = topic.GlossDef.Content
+	}
 	for _, node := range topic.Elements {
 		if IsBodyTag(node.XMLName.Local) {
 			if body != "" {
@@ -78,6 +152,7 @@ func (context *Context) Run() error {
 				continue
 			}
 			body = node.Content
+			context.Conditions, _ = context.ElementConditions(context.Conditions, node.XMLName.Local, node.Attrs)
 		}
 	}
 
@@ -87,6 +162,12 @@ func (context *Context) Run() error {
 
 	defer context.Encoder.Flush()
 
+	for _, term := range topic.Prolog.Keywords.IndexTerm {
+		if err := context.addPrologIndexTerm(term); err != nil {
+			return err
+		}
+	}
+
 	if topic.ShortDesc.Content != "" {
 		context.Encoder.WriteStart("p",
 			xml.Attr{Name: xml.Name{Local: "class"}, Value: "synopsis"})
@@ -102,6 +183,18 @@ func (context *Context) Run() error {
 		return err
 	}
 
+	// add nested topics
+	for _, node := range topic.Elements {
+		start := xml.StartElement{Name: node.XMLName, Attr: node.Attrs}
+		if !IsSpecializationOf(&start, "topic") {
+			continue
+		}
+		dec := xml.NewDecoder(strings.NewReader(node.Content))
+		if err := context.Handle(dec, start); err != nil {
+			return err
+		}
+	}
+
 	// add related links
 	return nil
 }
@@ -168,6 +261,30 @@ func (context *Context) Handle(dec *xml.Decoder, token xml.Token) error {
 		return context.HandleConref(dec, token.(xml.StartElement))
 	}
 
+	// track conditions inherited by the children
+	if start, isStart := token.(xml.StartElement); isStart {
+		conditions := ConditionsFromAttrs(start.Attr)
+		if err := context.Index.Scheme.Validate(conditions); err != nil {
+			context.errorf("<%s>: %v", start.Name.Local, err)
+		}
+
+		inherited := context.Conditions
+		context.Conditions = inherited.Cascade(conditions, getAttr(&start, "cascade") != "nomerge")
+		defer func() { context.Conditions = inherited }()
+
+		// track ids for reporting locations
+		if id := getAttr(&start, "id"); id != "" {
+			context.elementIDs = append(context.elementIDs, id)
+			defer func() { context.elementIDs = context.elementIDs[:len(context.elementIDs)-1] }()
+		}
+
+		// track title nesting
+		if isSectionLike(&start) {
+			context.sectionDepth++
+			defer func() { context.sectionDepth-- }()
+		}
+	}
+
 	startdepth := context.Encoder.Depth()
 	defer func() {
 		if startdepth != context.Encoder.Depth() {
@@ -176,6 +293,16 @@ func (context *Context) Handle(dec *xml.Decoder, token xml.Token) error {
 		}
 	}()
 
+	// should we wrap the tag?
+	if start, isStart := token.(xml.StartElement); isStart {
+		if wrap, ok := context.Rules.Wrap[context.Rules.RuleName(&start)]; ok {
+			wrapper := xml.StartElement{Name: xml.Name{Local: wrap.Name}}
+			setAttr(&wrapper, "class", wrap.AddClass)
+			context.check(context.Encoder.Encode(wrapper))
+			defer func() { context.check(context.Encoder.WriteEnd(wrap.Name)) }()
+		}
+	}
+
 	// should we unwrap the tag?
 	if context.ShouldUnwrap(token) {
 		return context.Recurse(dec)
@@ -183,17 +310,34 @@ func (context *Context) Handle(dec *xml.Decoder, token xml.Token) error {
 
 	// is it a starting token?
 	if start, isStart := token.(xml.StartElement); isStart {
+		tag := context.Rules.RuleName(&start)
+		name := start.Name.Local
+		if !context.Rules.HasRule(tag) && !htmlIdentical[tag] {
+			return context.HandleUnknown(dec, start)
+		}
+
+		if IsDITAClass(getAttr(&start, "class")) {
+			setAttr(&start, "class", "")
+		}
+
 		// is it custom already before naming
-		if process, isCustom := context.Rules.Custom[start.Name.Local]; isCustom {
+		if process, isCustom := context.Rules.Custom[tag]; isCustom {
+			// the class ancestry is gone, processors see the rule name
+			start.Name.Local = tag
+			context.Rules.ModifyAttributes(&start, tag, name)
 			return process(context, dec, start)
 		}
 
 		// handle tag renaming
-		if renaming, ok := context.Rules.Rename[start.Name.Local]; ok {
+		if renaming, ok := context.Rules.Rename[tag]; ok {
 			// setAttr(&start, "data-dita", start.Name.Local)
 			start.Name.Local = renaming.Name
 			setAttr(&start, "class", renaming.AddClass)
+		} else if htmlIdentical[tag] {
+			// specialization of an element that has the same name in html
+			start.Name.Local = tag
 		}
+		context.Rules.ModifyAttributes(&start, tag, name)
 
 		// is it custom after renaming?
 		if process, isCustom := context.Rules.Custom[start.Name.Local]; isCustom {
@@ -207,30 +351,52 @@ func (context *Context) Handle(dec *xml.Decoder, token xml.Token) error {
 	return context.Encoder.Encode(token)
 }
 
+// InlinedImageURL returns image href as a data url, the type is detected
+// from the content
 func (context *Context) InlinedImageURL(href string) string {
-	if strings.HasPrefix(href, "http:") || strings.HasPrefix(href, "https:") {
+	if isExternalURL(href) {
 		return href
 	}
 
 	directory := path.Dir(context.DecodingPath)
-	name := path.Join(directory, href)
+	name := path.Join(directory, trimLink(href))
 	data, _, err := context.Index.ReadFile(name)
 	if err != nil {
 		context.errorf("invalid image link %s: %s", href, err)
 		return href
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(data)
-	ext := strings.ToLower(path.Ext(name))
-	if ext == "" {
-		context.errorf("invalid image link: %s", href)
+	url, err := imagemap.DataURL(data)
+	if err != nil {
+		context.errorf("unable to inline image %s: %v", href, err)
+		return href
+	}
+	return url
+}
+
+// ImageURL returns the url for image href, images up to InlineImageLimit
+// bytes are inlined as data urls and others are output as assets
+func (context *Context) ImageURL(href string) string {
+	if context.InlineImageLimit <= 0 || href == "" || isExternalURL(href) {
+		return context.AssetURL("image", href)
+	}
+
+	name := path.Join(path.Dir(context.DecodingPath), trimLink(href))
+	data, _, err := context.Index.ReadFile(name)
+	if err != nil {
+		context.errorf("invalid image link %s: %s", href, err)
 		return href
 	}
+	if int64(len(data)) > context.InlineImageLimit {
+		return context.AssetURL("image", href)
+	}
 
-	if ext == "jpg" {
-		ext = "jpeg"
+	url, err := imagemap.DataURL(data)
+	if err != nil {
+		context.errorf("unable to inline image %s: %v", href, err)
+		return context.AssetURL("image", href)
 	}
-	return "data:image/" + ext + ";base64," + encoded
+	return url
 }
 
 func (context *Context) ResolveLinkInfo(url string) (href, title, synopsis string, internal bool) {
@@ -277,29 +443,74 @@ func (context *Context) ResolveLinkInfo(url string) (href, title, synopsis strin
 	return trimext(url) + ".html", title, synopsis, true
 }
 
+// ResolveKeyLink returns a link relative to the current topic for keyref,
+// "key/element" refers to an element in the topic defined by key
+func (context *Context) ResolveKeyLink(keyref string) string {
+	items := strings.SplitN(keyref, "/", 2)
+	topic := context.ResolveKeyTopic(items[0])
+	if topic == nil {
+		return ""
+	}
+
+	link, err := filepath.Rel(filepath.FromSlash(path.Dir(context.DecodingPath)), filepath.FromSlash(topic.Path))
+	if err != nil {
+		context.errorf("unable to link to key %v: %v", keyref, err)
+		return ""
+	}
+	link = filepath.ToSlash(link)
+
+	if len(items) == 2 && topic.Original != nil {
+		link += "#" + topic.Original.ID + "/" + items[1]
+	}
+	return link
+}
+
 func (context *Context) ShouldSkip(token xml.Token) bool {
 	start, isStart := token.(xml.StartElement)
 	if !isStart {
 		return false
 	}
 
-	if context.Rules.Skip[start.Name.Local] {
+	if context.Rules.Skip[context.Rules.RuleName(&start)] {
+		return true
+	}
+
+	// element is excluded by its own values or by the inherited values
+	conditions := ConditionsFromAttrs(start.Attr)
+	if !context.Index.IsIncluded(conditions) {
 		return true
 	}
 
-	if !isWebAudience(getAttr(&start, "audience"), getAttr(&start, "print"), getAttr(&start, "deliveryTarget")) {
+	inherited := context.Conditions.Cascade(conditions, getAttr(&start, "cascade") != "nomerge")
+	if !context.Index.IsIncluded(inherited) {
 		return true
 	}
 
 	return false
 }
 
+// ElementConditions cascades the conditions of an element that is not
+// processed by Handle, e.g. a decoded table row, from inherited. Values
+// not allowed by the subject scheme are reported. The element is included
+// when both its own and the cascaded conditions are included.
+func (context *Context) ElementConditions(inherited Conditions, name string, attrs []xml.Attr) (cascaded Conditions, included bool) {
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	conditions := ConditionsFromAttrs(attrs)
+	if err := context.Index.Scheme.Validate(conditions); err != nil {
+		context.errorf("<%s>: %v", name, err)
+	}
+
+	cascaded = inherited.Cascade(conditions, getAttr(&start, "cascade") != "nomerge")
+	included = context.Index.IsIncluded(conditions) && context.Index.IsIncluded(cascaded)
+	return cascaded, included
+}
+
 func (context *Context) ShouldUnwrap(token xml.Token) bool {
 	start, isStart := token.(xml.StartElement)
 	if !isStart {
 		return false
 	}
-	return context.Rules.Unwrap[start.Name.Local]
+	return context.Rules.Unwrap[context.Rules.RuleName(&start)]
 }
 
 func IsConref(token xml.Token) bool {
@@ -0,0 +1,32 @@
+package ditaconvert
+
+import "testing"
+
+// convertTopic converts topic name from files, the map is "m.ditamap"
+// and "filter.ditaval" is used for filtering when present
+func convertTopic(t *testing.T, files VFS, name string) *Context {
+	t.Helper()
+
+	index := NewIndex(files)
+	if _, ok := files["filter.ditaval"]; ok {
+		index.LoadDitaval("filter.ditaval")
+	}
+	index.LoadMap("m.ditamap")
+	for _, err := range index.Errors {
+		t.Errorf("loading map: %v", err)
+	}
+
+	topic, ok := index.Topics[CanonicalPath(name)]
+	if !ok {
+		t.Fatalf("topic %v not loaded", name)
+	}
+
+	context := NewConversion(index, topic)
+	if err := context.Run(); err != nil {
+		t.Fatalf("converting %v: %v", name, err)
+	}
+	for _, err := range context.Errors {
+		t.Errorf("converting %v: %v", name, err)
+	}
+	return context
+}
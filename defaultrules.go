@@ -34,6 +34,8 @@ func RenameWithClass(tag string, class string) TokenProcessor {
 
 func NewDefaultRules() *Rules {
 	return &Rules{
+		Unknown: UnknownAsElement,
+
 		Rename: map[string]Renaming{
 			// conversion
 			"xref": {"a", ""},
@@ -42,11 +44,11 @@ func NewDefaultRules() *Rules {
 			//lists
 			"choices":         {"ul", ""},
 			"choice":          {"li", ""},
-			"steps-unordered": {"ul", ""},
-			"steps":           {"ol", ""},
-			"step":            {"li", ""}, // handled with a custom rule
-			"substeps":        {"ol", ""},
-			"substep":         {"li", ""}, // handled with a custom rule
+			"steps-unordered": {"ul", "steps-unordered"}, // handled with a custom rule
+			"steps":           {"ol", "steps"},           // handled with a custom rule
+			"step":            {"li", "step"},            // handled with a custom rule
+			"substeps":        {"ol", "substeps"},
+			"substep":         {"li", "substep"}, // handled with a custom rule
 
 			"b":     {"strong", ""},
 			"i":     {"em", ""},
@@ -71,27 +73,42 @@ func NewDefaultRules() *Rules {
 			"delim":    {"span", ""},
 			"sep":      {"span", ""},
 			"parmname": {"span", ""},
+			"kwd":      {"kbd", "kwd"},
+			"var":      {"var", "var"},
+			"oper":     {"span", "oper"},
 
 			"userinput": {"kbd", "userinput"},
 
+			// base elements, specializations of these are handled
+			// by the class attribute
+			"ph":        {"span", "ph"},
+			"keyword":   {"span", "keyword"},
+			"tm":        {"span", "tm"},
+			"state":     {"span", "state"},
+			"cite":      {"cite", ""},
+			"lq":        {"blockquote", "lq"},
+			"sl":        {"ul", "sl"},
+			"sli":       {"li", ""},
+			"itemgroup": {"div", "itemgroup"},
+			"figgroup":  {"div", "figgroup"},
+			"abstract":  {"div", "abstract"},
+
 			"image": {"img", ""},
 
 			// ui
 			"uicontrol": {"b", ""},
 
 			// divs
-			"context":    {"div", ""},
-			"result":     {"div", ""},
-			"stepresult": {"div", ""},
-			"stepxmp":    {"div", ""},
-			"info":       {"div", ""},
-			"note":       {"div", ""},
-			"refsyn":     {"div", ""},
-			"bodydiv":    {"div", ""},
-			"fig":        {"div", "figure"},
-
-			"prereq":  {"div", ""},
-			"postreq": {"div", ""},
+			"stepresult":          {"div", "stepresult"},
+			"stepxmp":             {"div", "stepxmp"},
+			"info":                {"div", "info"},
+			"stepsection":         {"div", "stepsection"},
+			"steptroubleshooting": {"div", "steptroubleshooting"},
+			"tutorialinfo":        {"div", "tutorialinfo"},
+			"steps-informal":      {"div", "section steps-informal"},
+			"note":                {"div", ""},
+			"refsyn":              {"div", ""},
+			"bodydiv":             {"div", ""},
 
 			"colspec": {"colgroup", ""},
 
@@ -123,21 +140,39 @@ func NewDefaultRules() *Rules {
 			"section":    {"div", "section"},
 			"example":    {"div", "example"},
 			"sectiondiv": {"div", ""},
-			"title":      {"h2", "sectiontitle"},
 
-			// ??
-			"dlentry": {"div", ""},
+			// definition lists, div is allowed to group dt and dd in dl
+			"dlhead":  {"div", "dlhead"},
+			"dthd":    {"dt", "dthd"},
+			"ddhd":    {"dd", "ddhd"},
+			"dlentry": {"div", "dlentry"},
 			"dt":      {"dt", "dlterm"},
+
+			// parameter lists
+			"parml":   {"dl", "parml"},
+			"plentry": {"div", "plentry"},
+			"pt":      {"dt", "pt"},
+			"pd":      {"dd", "pd"},
+
+			// hazard statements
+			"messagepanel": {"div", "messagepanel"},
+			"typeofhazard": {"div", "typeofhazard"},
+			"consequence":  {"div", "consequence"},
+			"howtoavoid":   {"div", "howtoavoid"},
+			"hazardsymbol": {"img", "hazardsymbol"},
 		},
 		Skip: map[string]bool{
-			"br":            true,
-			"draft-comment": true,
+			"br":               true,
+			"draft-comment":    true,
+			"required-cleanup": true,
+			"indextermref":     true,
 
 			// RAINTREE SPECIFIC
 			"settinghead": true,
 		},
 		Unwrap: map[string]bool{
 			"tgroup": true,
+			"text":   true,
 		},
 		Custom: map[string]TokenProcessor{
 			"a": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
@@ -145,7 +180,20 @@ func NewDefaultRules() *Rules {
 				var internal bool
 
 				href = getAttr(&start, "href")
-				if href != "" {
+
+				// empty references to figures and tables show the label
+				label := ""
+				if href != "" && getAttr(&start, "scope") != "external" {
+					label = context.ResolveLabel(href)
+				}
+
+				// links to other files are downloads
+				format := strings.ToLower(getAttr(&start, "format"))
+				if href != "" && format != "" && format != "dita" && format != "ditamap" && format != "html" &&
+					getAttr(&start, "scope") != "external" && !isExternalURL(href) {
+					href = context.AssetURL("download", href)
+					setAttr(&start, "href", href)
+				} else if href != "" {
 					href, _, desc, internal = context.ResolveLinkInfo(href)
 					setAttr(&start, "href", href)
 				}
@@ -169,29 +217,15 @@ func NewDefaultRules() *Rules {
 					}
 				}
 
-				return context.EmitWithChildren(dec, start)
-			},
-			"img": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
-				href := getAttr(&start, "href")
-				//setAttr(&start, "src", context.InlinedImageURL(href))
-				setAttr(&start, "src", href)
-				setAttr(&start, "href", "")
-
-				placement := getAttr(&start, "placement")
-				setAttr(&start, "placement", "")
-				if placement == "break" {
-					context.Encoder.WriteStart("p",
-						xml.Attr{Name: xml.Name{Local: "class"}, Value: "image"})
-				}
-
-				err := context.EmitWithChildren(dec, start)
-
-				if placement == "break" {
-					context.Encoder.WriteEnd("p")
+				context.check(context.Encoder.Encode(start))
+				err, count := context.RecurseChildCount(dec)
+				if count == 0 && label != "" {
+					context.check(context.Encoder.WriteRaw(html.EscapeCharData(label)))
 				}
-
+				context.check(context.Encoder.Encode(xml.EndElement{Name: start.Name}))
 				return err
 			},
+			"img": HandleImage,
 			"data": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
 				datatype := strings.ToLower(getAttr(&start, "datatype"))
 				if datatype == "rttutorial" {
@@ -204,7 +238,7 @@ func NewDefaultRules() *Rules {
 						`		<p>Video playback not supported</p>` +
 						`</video>`
 
-					srcurl := html.NormalizeURL(href)
+					srcurl := html.NormalizeURL(context.AssetURL("video", href))
 
 					context.Encoder.WriteRaw(fmt.Sprintf(videof, srcurl))
 					return nil
@@ -212,7 +246,14 @@ func NewDefaultRules() *Rules {
 
 				return context.EmitWithChildren(dec, start)
 			},
-			"imagemap": ConvertImageMap,
+			"title":     HandleTitle,
+			"topic":     HandleNestedTopic,
+			"fig":       HandleFigure,
+			"imagemap":  ConvertImageMap,
+			"indexterm": HandleIndexTerm,
+
+			"term":             HandleTerm,
+			"abbreviated-form": HandleAbbreviatedForm,
 
 			"note": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
 				typ := getAttr(&start, "type")
@@ -330,33 +371,32 @@ func NewDefaultRules() *Rules {
 			},
 
 			"simpletable": HandleSimpleTable,
+			"choicetable": HandleSimpleTable,
+			"properties":  HandleSimpleTable,
 			"table":       HandleTable,
 			"settings":    HandleSettings,
 
-			"step": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
-				start.Name.Local = "li"
+			"steps":           HandleSteps,
+			"steps-unordered": HandleSteps,
+			"step":            HandleStep,
+			"substep":         HandleStep,
 
-				context.check(context.Encoder.Encode(start))
-				if getAttr(&start, "importance") == "optional" {
-					context.Encoder.WriteRaw("(Optional) ")
-				}
-				err := context.Recurse(dec)
-				context.check(context.Encoder.Encode(xml.EndElement{start.Name}))
+			"prereq":              LabeledSection(TaskLabels),
+			"context":             LabeledSection(TaskLabels),
+			"result":              LabeledSection(TaskLabels),
+			"postreq":             LabeledSection(TaskLabels),
+			"tasktroubleshooting": LabeledSection(TaskLabels),
 
-				return err
-			},
-			"substep": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
-				start.Name.Local = "li"
+			"syntaxdiagram": HandleSyntaxDiagram,
 
-				context.check(context.Encoder.Encode(start))
-				if getAttr(&start, "importance") == "optional" {
-					context.Encoder.WriteRaw("(Optional) ")
-				}
-				err := context.Recurse(dec)
-				context.check(context.Encoder.Encode(xml.EndElement{start.Name}))
+			"condition":        LabeledSection(TroubleshootingLabels),
+			"cause":            LabeledSection(TroubleshootingLabels),
+			"remedy":           LabeledSection(TroubleshootingLabels),
+			"troubleSolution":  HandleTroubleSolution,
+			"responsibleParty": HandleResponsibleParty,
+
+			"hazardstatement": HandleHazardStatement,
 
-				return err
-			},
 			"p": func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
 
 				err := context.EmitWithChildren(dec, start)
@@ -0,0 +1,12 @@
+package dita
+
+// Ditaval represents a .ditaval filtering file
+type Ditaval struct {
+	Props []DitavalProp `xml:"prop"`
+}
+
+type DitavalProp struct {
+	Att    string `xml:"att,attr"`
+	Val    string `xml:"val,attr"`
+	Action string `xml:"action,attr"`
+}
@@ -10,6 +10,15 @@ type MapNode struct {
 	NavTitle string `xml:"navtitle,attr"`
 	Href     string `xml:"href,attr"`
 	Keys     string `xml:"keys,attr"`
+	KeyRef   string `xml:"keyref,attr"`
+
+	// used by attributedef in subject scheme maps
+	Name string `xml:"name,attr"`
+
+	// used by bookmap
+	MainBookTitle InnerXML `xml:"booktitle>mainbooktitle"`
+
+	TopicMeta *TopicMeta `xml:"topicmeta"`
 
 	Type     string         `xml:"type,attr"`
 	CollType CollectionType `xml:"collection-type,attr"`
@@ -24,9 +33,51 @@ type MapNode struct {
 	DeliveryTarget string `xml:"deliveryTarget,attr"`
 	ProcessRole    string `xml:"processing-role,attr"`
 
+	Platform   string `xml:"platform,attr"`
+	Product    string `xml:"product,attr"`
+	Props      string `xml:"props,attr"`
+	OtherProps string `xml:"otherprops,attr"`
+	Rev        string `xml:"rev,attr"`
+	Importance string `xml:"importance,attr"`
+	Lang       string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Cascade    string `xml:"cascade,attr"`
+
 	Children []*MapNode `xml:",any"`
 }
 
+// TopicMeta represents <topicmeta> inside a topicref
+type TopicMeta struct {
+	NavTitle  string      `xml:"navtitle"`
+	LinkText  string      `xml:"linktext"`
+	ShortDesc InnerXML    `xml:"shortdesc"`
+	Keywords  Keywords    `xml:"keywords"`
+	OtherMeta []OtherMeta `xml:"othermeta"`
+	Audience  []Audience  `xml:"audience"`
+}
+
+// Attrs returns the cascading metadata attributes of the node
+func (node *MapNode) Attrs() []xml.Attr {
+	var attrs []xml.Attr
+	add := func(name, value string) {
+		if value != "" {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+		}
+	}
+
+	add("audience", node.Audience)
+	add("print", node.Print)
+	add("deliveryTarget", node.DeliveryTarget)
+	add("platform", node.Platform)
+	add("product", node.Product)
+	add("props", node.Props)
+	add("otherprops", node.OtherProps)
+	add("rev", node.Rev)
+	add("importance", node.Importance)
+	add("xml:lang", node.Lang)
+
+	return attrs
+}
+
 type CollectionType string
 
 const (
@@ -12,18 +12,68 @@ type Topic struct {
 	Prolog    Prolog   `xml:"prolog"`
 	ShortDesc InnerXML `xml:"shortdesc"`
 
+	// attributes of the topic element, e.g. conditions
+	Attrs []xml.Attr `xml:",any,attr"`
+
+	// glossentry
+	GlossTerm InnerXML  `xml:"glossterm"`
+	GlossDef  InnerXML  `xml:"glossdef"`
+	GlossBody GlossBody `xml:"glossBody"`
+
 	RelatedLink []Link `xml:"related-links>link"`
 	Elements    []Body `xml:",any"`
 }
 
 type Prolog struct {
-	Keywords   []string    `xml:"metadata>keywords>keyword"`
+	Keywords   Keywords    `xml:"metadata>keywords"`
 	OtherMeta  []OtherMeta `xml:"metadata>othermeta"`
+	Audience   []Audience  `xml:"metadata>audience"`
 	ResourceID []struct {
 		Name string `xml:"id,attr"`
 	} `xml:"resourceid"`
 }
 
+type GlossBody struct {
+	SurfaceForm  string `xml:"glossSurfaceForm"`
+	Abbreviation string `xml:"glossAlt>glossAbbreviation"`
+	Acronym      string `xml:"glossAlt>glossAcronym"`
+	ShortForm    string `xml:"glossAlt>glossShortForm"`
+}
+
+// AlternateForm returns the preferred abbreviated form of the term
+func (gloss *GlossBody) AlternateForm() string {
+	switch {
+	case gloss.Abbreviation != "":
+		return gloss.Abbreviation
+	case gloss.Acronym != "":
+		return gloss.Acronym
+	}
+	return gloss.ShortForm
+}
+
+type Keywords struct {
+	Keyword   []string    `xml:"keyword"`
+	IndexTerm []IndexTerm `xml:"indexterm"`
+}
+
+// IndexTerm is an indexterm in the prolog, the content is decoded
+// after conditional processing
+type IndexTerm struct {
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+// Terms returns all non-empty keywords
+func (keywords *Keywords) Terms() []string {
+	var terms []string
+	for _, keyword := range keywords.Keyword {
+		if keyword != "" {
+			terms = append(terms, keyword)
+		}
+	}
+	return terms
+}
+
 type InnerXML struct {
 	XMLName xml.Name
 	Content string `xml:",innerxml"`
@@ -33,7 +83,8 @@ func (x *InnerXML) Text() (string, error) { return xmlstriptags(x.Content) }
 
 type Body struct {
 	XMLName xml.Name
-	Content string `xml:",innerxml"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type Link struct {
@@ -47,3 +98,12 @@ type OtherMeta struct {
 	Name    string `xml:"name,attr"`
 	Content string `xml:"content,attr"`
 }
+
+type Audience struct {
+	Type            string `xml:"type,attr"`
+	OtherType       string `xml:"othertype,attr"`
+	Job             string `xml:"job,attr"`
+	OtherJob        string `xml:"otherjob,attr"`
+	ExperienceLevel string `xml:"experiencelevel,attr"`
+	Name            string `xml:"name,attr"`
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert"
+)
+
+// AssetCopier copies images, videos and downloads referenced by topics
+// into the output directory
+type AssetCopier struct {
+	Index *ditaconvert.Index
+	Dir   string
+	// Hash names the copies by their content, e.g. "_assets/3f2a...png"
+	Hash bool
+
+	// source path --> output path
+	hashed map[string]string
+	copied map[string]bool
+}
+
+func NewAssetCopier(index *ditaconvert.Index, dir string, hash bool) *AssetCopier {
+	return &AssetCopier{
+		Index:  index,
+		Dir:    dir,
+		Hash:   hash,
+		hashed: make(map[string]string),
+		copied: make(map[string]bool),
+	}
+}
+
+// OutputPath returns the location of asset in the output
+func (copier *AssetCopier) OutputPath(asset ditaconvert.Asset) string {
+	if !copier.Hash {
+		return asset.Path
+	}
+	if output, ok := copier.hashed[asset.Path]; ok {
+		return output
+	}
+
+	data, _, err := copier.Index.ReadFile(asset.Path)
+	if err != nil {
+		// missing files are reported when copying
+		return asset.Path
+	}
+
+	sum := sha256.Sum256(data)
+	output := path.Join("_assets", hex.EncodeToString(sum[:8])+strings.ToLower(path.Ext(asset.Path)))
+	copier.hashed[asset.Path] = output
+	return output
+}
+
+// Copy copies the assets not yet copied
+func (copier *AssetCopier) Copy(assets []ditaconvert.Asset) (errs []error) {
+	for _, asset := range assets {
+		if copier.copied[asset.Output] {
+			continue
+		}
+		copier.copied[asset.Output] = true
+
+		data, _, err := copier.Index.ReadFile(asset.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("missing %s %s: %v", asset.Kind, asset.Path, err))
+			continue
+		}
+
+		filename := filepath.Join(copier.Dir, filepath.FromSlash(asset.Output))
+		os.MkdirAll(filepath.Dir(filename), 0755)
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -14,7 +16,35 @@ import (
 	"github.com/raintreeinc/ditaconvert/html"
 )
 
+var (
+	ditaval = flag.String("ditaval", "", "DITAVAL file for filtering content")
+	scheme  = flag.String("scheme", "", "subject scheme map for validating attribute values")
+	rules   = flag.String("rules", "", "JSON file with conversion rules merged on top of the defaults")
+
+	headingLevel = flag.Int("heading-level", ditaconvert.DefaultHeadingLevel, "heading level of topic titles, when embedding into an existing page, earlier versions used 3")
+	numbering    = flag.String("numbering", "", "number figures and tables per \"topic\" or per \"publication\"")
+	inlineImages = flag.Int64("inline-images", 0, "inline images up to this many bytes as data urls, 0 disables inlining")
+	srcset       = flag.String("srcset", "", "comma separated widths of downscaled image copies for srcset, e.g. \"480,960\"")
+	assets       = flag.String("assets", "copy", "output images, videos and downloads at their source location with \"copy\" or named by content with \"hash\"")
+)
+
+// conversionRules are used for all topics, nil uses the defaults
+var conversionRules *ditaconvert.Rules
+
+// assetCopier copies the files referenced by the topics to the output
+var assetCopier *AssetCopier
+
+// imageVariants creates the srcset images, nil disables srcset
+var imageVariants *ImageVariants
+
 func main() {
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "usage: dita2html [flags] root.ditamap")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
 	fmt.Println(strings.Repeat("\n", 10))
 	fmt.Println("<< START >>")
 	start := time.Now()
@@ -22,8 +52,46 @@ func main() {
 		fmt.Printf("<< DONE %v >>\n", time.Since(start))
 	}()
 
-	root := os.Args[1]
-	index := ditaconvert.NewIndex(ditaconvert.Dir(filepath.Dir(root)))
+	if *rules != "" {
+		var err error
+		conversionRules, err = LoadRules(*rules)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	root := flag.Arg(0)
+	rootdir := filepath.Dir(root)
+	index := ditaconvert.NewIndex(ditaconvert.Dir(rootdir))
+	if *scheme != "" {
+		index.LoadSubjectScheme(RelativeTo(rootdir, *scheme))
+	}
+	if *ditaval != "" {
+		index.LoadDitaval(RelativeTo(rootdir, *ditaval))
+	}
+	switch ditaconvert.Numbering(*numbering) {
+	case ditaconvert.NumberingNone, ditaconvert.NumberingTopic, ditaconvert.NumberingPublication:
+		index.Numbering = ditaconvert.Numbering(*numbering)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid numbering %q\n", *numbering)
+		os.Exit(1)
+	}
+	switch *assets {
+	case "copy", "hash":
+		assetCopier = NewAssetCopier(index, "output~", *assets == "hash")
+	default:
+		fmt.Fprintf(os.Stderr, "invalid assets %q\n", *assets)
+		os.Exit(1)
+	}
+	if *srcset != "" {
+		widths, err := ParseWidths(*srcset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid srcset: %v\n", err)
+			os.Exit(1)
+		}
+		imageVariants = NewImageVariants(index, "output~", widths)
+	}
 	index.LoadMap(filepath.ToSlash(filepath.Base(root)))
 
 	for _, err := range index.Errors {
@@ -35,6 +103,32 @@ func main() {
 		filename := path.Join("output~", ReplaceExt(topic.Path, ".html"))
 		WriteTopic(index, topic, filepath.FromSlash(filename))
 	}
+	WriteIndex(index, filepath.FromSlash("output~/_index.html"))
+	WriteGlossary(index, filepath.FromSlash("output~/_glossary.html"))
+
+	PrintUnknownReport(index)
+}
+
+func PrintUnknownReport(index *ditaconvert.Index) {
+	unknown := index.UnknownElements()
+	if len(unknown) == 0 {
+		return
+	}
+
+	fmt.Println("Unhandled elements:")
+	for _, element := range unknown {
+		fmt.Printf("\t<%s> %dx\n", element.Name, element.Count)
+		for _, sample := range element.Samples {
+			fmt.Printf("\t\t%s\n", sample)
+		}
+	}
+}
+
+// generatedPages maps booklist kinds to generated pages
+var generatedPages = map[string]string{
+	"toc":          "_toc.html",
+	"indexlist":    "_index.html",
+	"glossarylist": "_glossary.html",
 }
 
 func WriteTOC(entry *ditaconvert.Entry, filename string) {
@@ -53,11 +147,18 @@ func WriteTOC(entry *ditaconvert.Entry, filename string) {
 		if !entry.TOC {
 			return
 		}
-		if entry.Topic == nil {
-			fmt.Fprintf(out, `<li>%s`, html.EscapeString(entry.Title))
+		title := entry.Title
+		if entry.Number != "" {
+			title = entry.Number + ". " + title
+		}
+
+		if generated, ok := generatedPages[entry.Kind]; ok && entry.Topic == nil {
+			fmt.Fprintf(out, `<li class="%s"><a href="/%s">%s</a>`, entry.Kind, generated, html.EscapeString(title))
+		} else if entry.Topic == nil {
+			fmt.Fprintf(out, `<li>%s`, html.EscapeString(title))
 		} else {
 			newpath := ReplaceExt(entry.Topic.Path, ".html")
-			fmt.Fprintf(out, `<li><a href="/%s">%s</a>`, html.NormalizeURL(newpath), entry.Title)
+			fmt.Fprintf(out, `<li><a href="/%s">%s</a>`, html.NormalizeURL(newpath), title)
 		}
 
 		if len(entry.Children) > 0 {
@@ -72,6 +173,87 @@ func WriteTOC(entry *ditaconvert.Entry, filename string) {
 	PrintEntry(entry)
 }
 
+func WriteIndex(index *ditaconvert.Index, filename string) {
+	groups := index.IndexGroups()
+	if len(groups) == 0 && !index.HasBookList("indexlist") {
+		return
+	}
+
+	os.MkdirAll(filepath.Dir(filename), 0755)
+	out, err := os.Create(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+
+	fmt.Fprint(out, `<link rel="stylesheet" href="/style.css">`)
+	heading := ditaconvert.HeadingTag(*headingLevel)
+	fmt.Fprint(out, `<`+heading+`>Index</`+heading+`>`)
+
+	var PrintTerm func(term *ditaconvert.IndexTerm)
+	PrintTerm = func(term *ditaconvert.IndexTerm) {
+		fmt.Fprintf(out, `<li><span class="indexterm">%s</span>`, html.EscapeCharData(term.Term))
+		for i, target := range term.Targets {
+			href := html.NormalizeURL(ReplaceExt(target.Topic.Path, ".html"))
+			// prolog indexterms refer to the whole topic
+			if target.Anchor != "" {
+				href += "#" + target.Anchor
+			}
+			fmt.Fprintf(out, `, <a href="/%s" title="%s">%d</a>`,
+				href, html.EscapeAttribute(target.Topic.Title), i+1)
+		}
+		for _, see := range term.See {
+			fmt.Fprintf(out, `. <em>See</em> %s`, html.EscapeCharData(see))
+		}
+		for _, see := range term.SeeAlso {
+			fmt.Fprintf(out, `. <em>See also</em> %s`, html.EscapeCharData(see))
+		}
+
+		if len(term.Children) > 0 {
+			fmt.Fprint(out, `<ul>`)
+			for _, child := range term.Children {
+				PrintTerm(child)
+			}
+			fmt.Fprint(out, `</ul>`)
+		}
+		fmt.Fprint(out, "</li>")
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(out, `<div class="indexgroup"><%s>%s</%[1]s><ul>`, ditaconvert.HeadingTag(*headingLevel+1), html.EscapeCharData(group.Letter))
+		for _, term := range group.Terms {
+			PrintTerm(term)
+		}
+		fmt.Fprint(out, `</ul></div>`)
+	}
+}
+
+func WriteGlossary(index *ditaconvert.Index, filename string) {
+	entries := index.GlossEntries()
+	if len(entries) == 0 && !index.HasBookList("glossarylist") {
+		return
+	}
+
+	os.MkdirAll(filepath.Dir(filename), 0755)
+	out, err := os.Create(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+
+	fmt.Fprint(out, `<link rel="stylesheet" href="/style.css">`)
+	heading := ditaconvert.HeadingTag(*headingLevel)
+	fmt.Fprint(out, `<`+heading+`>Glossary</`+heading+`>`)
+	fmt.Fprint(out, `<dl class="glossary">`)
+	for _, topic := range entries {
+		newpath := ReplaceExt(topic.Path, ".html")
+		fmt.Fprintf(out, `<dt class="glossterm"><a href="/%s">%s</a></dt>`,
+			html.NormalizeURL(newpath), html.EscapeCharData(topic.Title))
+		fmt.Fprintf(out, `<dd class="glossdef">%s</dd>`, html.EscapeCharData(topic.Synopsis))
+	}
+	fmt.Fprint(out, `</dl>`)
+}
+
 func WriteTopic(index *ditaconvert.Index, topic *ditaconvert.Topic, filename string) {
 	os.MkdirAll(filepath.Dir(filename), 0755)
 	file, err := os.Create(filename)
@@ -84,7 +266,18 @@ func WriteTopic(index *ditaconvert.Index, topic *ditaconvert.Topic, filename str
 	defer out.Flush()
 
 	conversion := ditaconvert.NewConversion(index, topic)
-	if err := conversion.Run(); err != nil || len(conversion.Errors) > 0 {
+	conversion.HeadingLevel = *headingLevel
+	if conversionRules != nil {
+		conversion.Rules = conversionRules
+	}
+	conversion.InlineImageLimit = *inlineImages
+	conversion.AssetPath = assetCopier.OutputPath
+	if imageVariants != nil {
+		conversion.ImageVariants = imageVariants.Variants
+	}
+	err = conversion.Run()
+	conversion.Errors = append(conversion.Errors, assetCopier.Copy(conversion.Assets)...)
+	if err != nil || len(conversion.Errors) > 0 {
 		fmt.Printf("[%s] %s: %v\n", topic.Path, topic.Title, err)
 		for _, err := range conversion.Errors {
 			fmt.Printf("\t%v\n", err)
@@ -96,18 +289,34 @@ func WriteTopic(index *ditaconvert.Index, topic *ditaconvert.Topic, filename str
 
 	fmt.Fprint(out, "<!--INGREDIENTS:\n")
 	fmt.Fprint(out, "Keywords=")
-	for i, key := range topic.Original.Prolog.Keywords.Terms() {
+	keywords := append(topic.Original.Prolog.Keywords.Terms(), topic.Metadata.Keywords...)
+	for i, key := range keywords {
 		if i > 0 {
 			fmt.Fprint(out, ",")
 		}
 		fmt.Fprint(out, key)
 	}
-	for _, meta := range topic.Original.Prolog.OtherMeta {
+	fmt.Fprint(out, "\n")
+	if len(conversion.IndexTerms) > 0 {
+		fmt.Fprintf(out, "IndexTerms=%s\n", html.EscapeString(strings.Join(conversion.IndexTerms, ";")))
+	}
+	for _, meta := range append(topic.Original.Prolog.OtherMeta, topic.Metadata.OtherMeta...) {
 		fmt.Fprintf(out, "%s=%s\n", html.EscapeString(meta.Name), html.EscapeString(meta.Content))
 	}
+	if audience := append(topic.Original.Prolog.Audience, topic.Metadata.Audience...); len(audience) > 0 {
+		fmt.Fprint(out, "Audience=")
+		for i, aud := range audience {
+			if i > 0 {
+				fmt.Fprint(out, ",")
+			}
+			fmt.Fprint(out, html.EscapeString(aud.Type))
+		}
+		fmt.Fprint(out, "\n")
+	}
 	fmt.Fprint(out, "-->\n")
 	fmt.Fprint(out, `<body id="`+topic.Original.ID+`">`)
-	fmt.Fprint(out, `<h3>`+html.EscapeString(topic.Title)+`</h3>`)
+	heading := conversion.TopicHeading()
+	fmt.Fprint(out, `<`+heading+`>`+html.EscapeString(topic.Title)+`</`+heading+`>`)
 	fmt.Fprint(out, `<div>`)
 	fmt.Fprint(out, conversion.Output.String())
 	fmt.Fprint(out, `</div>`)
@@ -115,6 +324,32 @@ func WriteTopic(index *ditaconvert.Index, topic *ditaconvert.Topic, filename str
 	fmt.Fprint(out, `</body>`)
 }
 
+// LoadRules loads rules from filename and merges them with the defaults
+func LoadRules(filename string) (*ditaconvert.Rules, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	custom, err := ditaconvert.ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	rules := ditaconvert.NewDefaultRules()
+	rules.Merge(custom)
+	return rules, nil
+}
+
+// RelativeTo converts filename to a slash separated path relative to dir
+func RelativeTo(dir, filename string) string {
+	rel, err := filepath.Rel(dir, filename)
+	if err != nil {
+		return filepath.ToSlash(filename)
+	}
+	return filepath.ToSlash(rel)
+}
+
 func ReplaceExt(name string, newext string) string {
 	return name[:len(name)-len(path.Ext(name))] + newext
 }
@@ -154,8 +389,8 @@ func RelatedLinksAsHTML(context *ditaconvert.Context) (div string) {
 
 		for _, link := range set.Children {
 			div += `<li class="ulchildlink">` + LinkAsAnchorNoTitle(context, link)
-			if link.Topic.Synopsis != "" {
-				div += `<p>` + link.Topic.Synopsis + `</p>`
+			if synopsis := link.Synopsis(); synopsis != "" {
+				div += `<p>` + synopsis + `</p>`
 			}
 			div += `</li>`
 		}
@@ -187,7 +422,7 @@ func RelatedLinksAsHTML(context *ditaconvert.Context) (div string) {
 	}
 
 	grouped := make(map[string][]*ditaconvert.Link)
-	order := []string{"video", "concept", "task", "reference", "information"}
+	order := []string{"video", "concept", "task", "reference", "troubleshooting", "information"}
 	for _, set := range topic.Links {
 		for _, link := range set.Siblings {
 			kind := ""
@@ -220,7 +455,7 @@ func RelatedLinksAsHTML(context *ditaconvert.Context) (div string) {
 
 		if kind != "information" {
 			class := kindclass[kind]
-			if len(links) > 1 {
+			if len(links) > 1 && kind != "troubleshooting" {
 				kind += "s"
 			}
 			div += `<div class="relinfo ` + class + `"><strong>Related ` + kind + `</strong>`
@@ -242,6 +477,8 @@ var kindclass = map[string]string{
 	"reference": "relref",
 	"concept":   "relconcepts",
 	"task":      "reltasks",
+
+	"troubleshooting": "reltroubleshooting",
 }
 
 func LinkAsAnchorNoTitle(context *ditaconvert.Context, link *ditaconvert.Link) string {
@@ -280,7 +517,7 @@ func LinkAsAnchor(context *ditaconvert.Context, link *ditaconvert.Link) string {
 	)
 	ref := path.Join(reldir, trimext(path.Base(link.Topic.Path))+".html")
 
-	desc := link.Topic.Synopsis
+	desc := link.Synopsis()
 	if desc == "" {
 		return `<a href="` + html.NormalizeURL(ref) + `">` + title + `</a>`
 	}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert"
+)
+
+// ImageVariants generates downscaled copies of large PNG and JPEG images
+// for srcset. The copies are named by content and width, so images that
+// have not changed are not scaled again in the next build.
+type ImageVariants struct {
+	Index *ditaconvert.Index
+	Dir   string
+	// Widths of the generated copies, images narrower than a width
+	// are not scaled to it
+	Widths []int
+
+	// source path --> variants
+	generated map[string][]ditaconvert.ImageVariant
+}
+
+func NewImageVariants(index *ditaconvert.Index, dir string, widths []int) *ImageVariants {
+	sort.Ints(widths)
+	return &ImageVariants{
+		Index:     index,
+		Dir:       dir,
+		Widths:    widths,
+		generated: make(map[string][]ditaconvert.ImageVariant),
+	}
+}
+
+// ParseWidths parses comma separated widths, e.g. "480,960"
+func ParseWidths(value string) ([]int, error) {
+	var widths []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		width, err := strconv.Atoi(field)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid width %q", field)
+		}
+		widths = append(widths, width)
+	}
+	return widths, nil
+}
+
+// Variants returns the downscaled copies of asset, generating the missing ones
+func (variants *ImageVariants) Variants(asset ditaconvert.Asset, width, height int) []ditaconvert.ImageVariant {
+	if result, ok := variants.generated[asset.Path]; ok {
+		return result
+	}
+	result, err := variants.generate(asset, width, height)
+	if err != nil {
+		fmt.Printf("[%s] unable to create image variants: %v\n", asset.Path, err)
+	}
+	variants.generated[asset.Path] = result
+	return result
+}
+
+func (variants *ImageVariants) generate(asset ditaconvert.Asset, width, height int) ([]ditaconvert.ImageVariant, error) {
+	var widths []int
+	for _, w := range variants.Widths {
+		if w < width {
+			widths = append(widths, w)
+		}
+	}
+	if len(widths) == 0 {
+		return nil, nil
+	}
+
+	data, _, err := variants.Index.ReadFile(asset.Path)
+	if err != nil {
+		return nil, err
+	}
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") {
+		return nil, nil
+	}
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+
+	sum := sha256.Sum256(data)
+	prefix := hex.EncodeToString(sum[:8])
+
+	var src image.Image
+	var result []ditaconvert.ImageVariant
+	for _, w := range widths {
+		variant := ditaconvert.ImageVariant{
+			Output: path.Join("_variants", prefix+"-"+strconv.Itoa(w)+ext),
+			Width:  w,
+		}
+		filename := filepath.Join(variants.Dir, filepath.FromSlash(variant.Output))
+		if _, err := os.Stat(filename); err == nil {
+			result = append(result, variant)
+			continue
+		}
+
+		if src == nil {
+			src, _, err = image.Decode(bytes.NewReader(data))
+			if err != nil {
+				return result, err
+			}
+		}
+
+		h := (height*w + width/2) / width
+		if h < 1 {
+			h = 1
+		}
+		var out bytes.Buffer
+		scaled := downscale(src, w, h)
+		if format == "jpeg" {
+			err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&out, scaled)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		os.MkdirAll(filepath.Dir(filename), 0755)
+		if err := ioutil.WriteFile(filename, out.Bytes(), 0644); err != nil {
+			return result, err
+		}
+		result = append(result, variant)
+	}
+	return result, nil
+}
+
+// downscale resizes src to width x height by averaging the source
+// pixels covered by each destination pixel
+func downscale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// HandleFigure outputs fig as figure, the title and desc are output
+// as figcaption with the generated label, e.g. "Figure 3."
+func HandleFigure(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	id := getAttr(&start, "id")
+
+	start.Name.Local = "figure"
+	setAttr(&start, "class", "fig")
+	context.check(context.Encoder.Encode(start))
+	defer func() { context.check(context.Encoder.WriteEnd("figure")) }()
+
+	// title and desc are allowed only before the figure content
+	captioned, incaption := false, false
+	closeCaption := func() {
+		if incaption {
+			context.check(context.Encoder.WriteEnd("figcaption"))
+			incaption = false
+		}
+		captioned = true
+	}
+	openCaption := func() {
+		if !incaption {
+			context.check(context.Encoder.WriteStart("figcaption"))
+			incaption = true
+		}
+	}
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			closeCaption()
+			return nil
+		case xml.CharData:
+			if !captioned && strings.TrimSpace(string(token)) == "" {
+				continue
+			}
+			closeCaption()
+		case xml.StartElement:
+			if captioned || context.ShouldSkip(token) {
+				break
+			}
+
+			switch context.Rules.RuleName(&token) {
+			case "title":
+				openCaption()
+				context.writeCaptionLabel("fig", id)
+				if err := context.Recurse(dec); err != nil {
+					return err
+				}
+				continue
+			case "desc":
+				openCaption()
+				if err := context.emitDesc(dec, "figdesc"); err != nil {
+					return err
+				}
+				continue
+			}
+			closeCaption()
+		}
+
+		if err := context.Handle(dec, token); err != nil {
+			return err
+		}
+	}
+}
+
+// writeCaptionLabel writes the generated label for the next titled
+// element of kind, nothing is written when numbering is disabled
+func (context *Context) writeCaptionLabel(kind, id string) {
+	label := context.NextLabel(kind, id)
+	if label == "" {
+		return
+	}
+	context.check(context.Encoder.WriteStart("span", attr("class", kind+"label")))
+	context.check(context.Encoder.WriteRaw(html.EscapeCharData(label) + "."))
+	context.check(context.Encoder.WriteEnd("span"))
+	context.check(context.Encoder.WriteRaw(" "))
+}
+
+// emitDesc outputs the children of desc as a div with class
+func (context *Context) emitDesc(dec *xml.Decoder, class string) error {
+	context.check(context.Encoder.WriteStart("div", attr("class", class)))
+	err := context.Recurse(dec)
+	context.check(context.Encoder.WriteEnd("div"))
+	return err
+}
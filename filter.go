@@ -0,0 +1,114 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+)
+
+// Filter contains DITAVAL conditional processing actions
+type Filter struct {
+	// attribute --> value --> action
+	Actions map[string]map[string]string
+	// attribute --> action for values not listed
+	Defaults map[string]string
+	// action for attributes not listed
+	Default string
+}
+
+func NewFilter(val *dita.Ditaval) *Filter {
+	filter := &Filter{
+		Actions:  make(map[string]map[string]string),
+		Defaults: make(map[string]string),
+	}
+
+	for _, prop := range val.Props {
+		switch {
+		case prop.Att == "":
+			filter.Default = prop.Action
+		case prop.Val == "":
+			filter.Defaults[prop.Att] = prop.Action
+		default:
+			actions, ok := filter.Actions[prop.Att]
+			if !ok {
+				actions = make(map[string]string)
+				filter.Actions[prop.Att] = actions
+			}
+			actions[prop.Val] = prop.Action
+		}
+	}
+
+	return filter
+}
+
+// LoadDitaval loads filter from a path relative to FileSystem root
+func (index *Index) LoadDitaval(name string) {
+	data, _, err := index.ReadFile(name)
+	if err != nil {
+		index.check(fmt.Errorf("failed to read ditaval %s: %v", name, err))
+		return
+	}
+
+	val := &dita.Ditaval{}
+	if err := xml.Unmarshal(data, val); err != nil {
+		index.check(fmt.Errorf("failed to unmarshal ditaval %s: %v", name, err))
+		return
+	}
+
+	index.Filter = NewFilter(val)
+}
+
+// Action returns the action for the attribute value, when the value
+// is not listed, the closest listed parent subject from scheme is used.
+func (filter *Filter) Action(attribute, value string, scheme *SubjectScheme) string {
+	actions := filter.Actions[attribute]
+	if action, ok := actions[value]; ok {
+		return action
+	}
+
+	if scheme != nil {
+		for _, ancestor := range scheme.Ancestors(value) {
+			if action, ok := actions[ancestor]; ok {
+				return action
+			}
+		}
+	}
+
+	if action, ok := filter.Defaults[attribute]; ok {
+		return action
+	}
+	return filter.Default
+}
+
+// Excludes checks whether content with the conditions should be removed,
+// which happens when all values of some attribute are excluded.
+func (filter *Filter) Excludes(conditions Conditions, scheme *SubjectScheme) bool {
+	if filter == nil {
+		return false
+	}
+
+	for attribute := range conditions {
+		if !mergedAttributes[attribute] {
+			continue
+		}
+
+		values := conditions.Values(attribute)
+		excluded := 0
+		for _, value := range values {
+			if filter.Action(attribute, value, scheme) == "exclude" {
+				excluded++
+			}
+		}
+
+		if len(values) > 0 && excluded == len(values) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIncluded checks whether content with the conditions should be output
+func (index *Index) IsIncluded(conditions Conditions) bool {
+	return conditions.IsWebAudience() && !index.Filter.Excludes(conditions, index.Scheme)
+}
@@ -0,0 +1,147 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// IsGlossEntry checks whether topic is a glossentry
+func (topic *Topic) IsGlossEntry() bool {
+	return topic != nil && topic.Original != nil && topic.Original.XMLName.Local == "glossentry"
+}
+
+// glossTerm returns the text of the glossterm, including nested markup
+func glossTerm(topic *dita.Topic) string {
+	term, _ := topic.GlossTerm.Text()
+	return strings.Join(strings.Fields(term), " ")
+}
+
+// ResolveKeyTopic returns the topic defined by the key
+func (context *Context) ResolveKeyTopic(keyref string) *Topic {
+	key, _ := SplitLink(keyref)
+	key = strings.SplitN(key, "/", 2)[0]
+
+	abspath, ok := context.Index.KeyDef[key]
+	if !ok {
+		context.errorf("keydef missing for %v", keyref)
+		return nil
+	}
+
+	topic, ok := context.Index.Topics[CanonicalPath(abspath)]
+	if !ok {
+		context.errorf("did not find topic %v for key %v", abspath, keyref)
+		return nil
+	}
+	return topic
+}
+
+// useGlossEntry marks key as used and returns whether it was the first use in this topic
+func (context *Context) useGlossEntry(key string) (first bool) {
+	if context.glossaryUsed == nil {
+		context.glossaryUsed = make(map[string]bool)
+	}
+	first = !context.glossaryUsed[key]
+	context.glossaryUsed[key] = true
+	return first
+}
+
+// HandleTerm renders <term> with the glossary definition as a hover text
+func HandleTerm(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	keyref := getAttr(&start, "keyref")
+	setAttr(&start, "keyref", "")
+
+	start.Name.Local = "dfn"
+	setAttr(&start, "class", "term")
+
+	if keyref == "" {
+		return context.EmitWithChildren(dec, start)
+	}
+
+	topic := context.ResolveKeyTopic(keyref)
+	if !topic.IsGlossEntry() {
+		return context.EmitWithChildren(dec, start)
+	}
+	context.useGlossEntry(keyref)
+
+	if topic.Synopsis != "" {
+		setAttr(&start, "title", topic.Synopsis)
+	}
+
+	context.check(context.Encoder.Encode(start))
+	err, count := context.RecurseChildCount(dec)
+	if count == 0 {
+		context.check(context.Encoder.WriteRaw(html.EscapeCharData(topic.Title)))
+	}
+	context.check(context.Encoder.Encode(xml.EndElement{Name: start.Name}))
+	return err
+}
+
+// HandleAbbreviatedForm renders <abbreviated-form>, the first use in a topic
+// shows the surface form and later uses the abbreviated form of the glossentry.
+func HandleAbbreviatedForm(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	dec.Skip()
+
+	keyref := getAttr(&start, "keyref")
+	topic := context.ResolveKeyTopic(keyref)
+	if !topic.IsGlossEntry() {
+		context.errorf("abbreviated-form %v does not refer to a glossentry", keyref)
+		return nil
+	}
+
+	gloss := &topic.Original.GlossBody
+	term := glossTerm(topic.Original)
+
+	abbreviation := strings.TrimSpace(gloss.AlternateForm())
+	if abbreviation == "" {
+		abbreviation = term
+	}
+
+	if context.useGlossEntry(keyref) {
+		surface := strings.TrimSpace(gloss.SurfaceForm)
+		if surface == "" {
+			surface = term
+			if abbreviation != term {
+				surface += " (" + abbreviation + ")"
+			}
+		}
+
+		dfn := xml.StartElement{Name: xml.Name{Local: "dfn"}}
+		setAttr(&dfn, "class", "abbreviated-form")
+		setAttr(&dfn, "title", topic.Synopsis)
+		context.check(context.Encoder.Encode(dfn))
+		context.check(context.Encoder.WriteRaw(html.EscapeCharData(surface)))
+		context.check(context.Encoder.WriteEnd("dfn"))
+		return nil
+	}
+
+	context.check(context.Encoder.WriteStart("abbr",
+		attr("class", "abbreviated-form"),
+		attr("title", term)))
+	context.check(context.Encoder.WriteRaw(html.EscapeCharData(abbreviation)))
+	context.check(context.Encoder.WriteEnd("abbr"))
+	return nil
+}
+
+type topicsByTitle []*Topic
+
+func (xs topicsByTitle) Len() int      { return len(xs) }
+func (xs topicsByTitle) Swap(i, j int) { xs[i], xs[j] = xs[j], xs[i] }
+func (xs topicsByTitle) Less(i, j int) bool {
+	return strings.ToLower(xs[i].Title) < strings.ToLower(xs[j].Title)
+}
+
+// GlossEntries returns all loaded glossentries sorted by term
+func (index *Index) GlossEntries() []*Topic {
+	var entries []*Topic
+	for _, topic := range index.Topics {
+		if topic.IsGlossEntry() {
+			entries = append(entries, topic)
+		}
+	}
+	sort.Sort(topicsByTitle(entries))
+	return entries
+}
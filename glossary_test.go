@@ -0,0 +1,74 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyDefGlossEntries(t *testing.T) {
+	const glossentry = `<glossentry id="sso">
+		<glossterm>Single <b>sign-on</b></glossterm>
+		<glossdef>Login once</glossdef>
+		<glossBody><glossAlt><glossAcronym>SSO</glossAcronym></glossAlt></glossBody>
+	</glossentry>`
+
+	tests := []struct {
+		keydef string
+		file   string
+		loaded bool
+	}{
+		{`<keydef keys="sso" href="sso.dita"/>`, "sso.dita", true},
+		{`<keydef keys="sso" href="sso.xml"/>`, "sso.xml", true},
+		{`<keydef keys="sso" href="sso.glossary" format="dita"/>`, "sso.glossary", true},
+		{`<keydef keys="sso" href="sso.dita" format="html"/>`, "sso.dita", false},
+		{`<keydef keys="sso" href="sso.txt"/>`, "sso.txt", false},
+	}
+
+	for _, test := range tests {
+		index := NewIndex(VFS{
+			"m.ditamap": `<map>` + test.keydef + `</map>`,
+			test.file:   glossentry,
+		})
+		index.LoadMap("m.ditamap")
+		for _, err := range index.Errors {
+			t.Errorf("%s: %v", test.keydef, err)
+		}
+
+		topic, loaded := index.Topics[test.file]
+		if loaded != test.loaded {
+			t.Errorf("%s: loaded %v, expected %v", test.keydef, loaded, test.loaded)
+			continue
+		}
+		if loaded && topic.Title != "Single sign-on" {
+			t.Errorf("%s: got title %q, expected %q", test.keydef, topic.Title, "Single sign-on")
+		}
+	}
+}
+
+func TestAbbreviatedFormTerm(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap": `<map>
+			<keydef keys="sso" href="sso.dita"/>
+			<topicref href="t.dita"/>
+		</map>`,
+		"sso.dita": `<glossentry id="sso">
+			<glossterm>Single <b>sign-on</b></glossterm>
+			<glossdef>Login once</glossdef>
+			<glossBody><glossAlt><glossAcronym>SSO</glossAcronym></glossAlt></glossBody>
+		</glossentry>`,
+		"t.dita": `<topic id="t"><title>T</title><body>
+			<p><abbreviated-form keyref="sso"/> and <abbreviated-form keyref="sso"/></p>
+		</body></topic>`,
+	}, "t.dita")
+
+	output := context.Output.String()
+	tests := []string{
+		`>Single sign-on (SSO)</dfn>`,
+		`<abbr class="abbreviated-form" title="Single sign-on">SSO</abbr>`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q:\n%s", want, output)
+		}
+	}
+}
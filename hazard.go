@@ -0,0 +1,123 @@
+package ditaconvert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// HazardSignalWords contains the ANSI Z535 / ISO 3864 signal words
+// for hazardstatement types
+var HazardSignalWords = map[string]string{
+	"danger":    "DANGER",
+	"warning":   "WARNING",
+	"caution":   "CAUTION",
+	"notice":    "NOTICE",
+	"attention": "ATTENTION",
+	"important": "IMPORTANT",
+	"note":      "NOTE",
+	"remember":  "REMEMBER",
+	"tip":       "TIP",
+}
+
+// hazardAlert contains types that indicate a personal injury hazard,
+// these are preceded by the safety alert symbol
+var hazardAlert = map[string]bool{
+	"danger":  true,
+	"warning": true,
+	"caution": true,
+}
+
+// HandleHazardStatement outputs hazardstatement as a safety message:
+// a signal word panel followed by the hazard symbols and message panels.
+//
+//	<div class="hazardstatement hazardstatement-danger">
+//	  <div class="hazardsignal">⚠ DANGER</div>
+//	  <div class="hazardcontent">
+//	    <div class="hazardsymbols"><img class="hazardsymbol"></div>
+//	    <div class="messagepanel">...</div>
+//	  </div>
+//	</div>
+func HandleHazardStatement(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	typ := getAttr(&start, "type")
+	if typ == "" {
+		typ = "caution"
+	}
+	signal := HazardSignalWords[typ]
+	if typ == "other" {
+		typ = getAttr(&start, "othertype")
+		signal = strings.ToUpper(typ)
+	}
+	if signal == "" {
+		signal = strings.ToUpper(typ)
+	}
+
+	setAttr(&start, "type", "")
+	setAttr(&start, "othertype", "")
+
+	// symbols are output before the message panels,
+	// regardless of their position in the source
+	var symbols, panels bytes.Buffer
+	symbolsEncoder := html.NewEncoder(&symbols)
+	panelsEncoder := html.NewEncoder(&panels)
+
+	encoder := context.Encoder
+	err := func() error {
+		defer func() { context.Encoder = encoder }()
+		for {
+			token, err := dec.Token()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+
+			context.Encoder = panelsEncoder
+			switch token := token.(type) {
+			case xml.EndElement:
+				return nil
+			case xml.StartElement:
+				if context.Rules.RuleName(&token) == "hazardsymbol" {
+					context.Encoder = symbolsEncoder
+				}
+			case xml.CharData:
+				if strings.TrimSpace(string(token)) == "" {
+					continue
+				}
+			}
+
+			if err := context.Handle(dec, token); err != nil {
+				return err
+			}
+		}
+	}()
+	context.check(symbolsEncoder.Flush())
+	context.check(panelsEncoder.Flush())
+
+	start.Name.Local = "div"
+	setAttr(&start, "class", "hazardstatement hazardstatement-"+typ)
+	context.check(context.Encoder.Encode(start))
+
+	context.check(context.Encoder.WriteStart("div", attr("class", "hazardsignal")))
+	if hazardAlert[typ] {
+		context.check(context.Encoder.WriteRaw(`<span class="hazardalert" aria-hidden="true">&#x26a0;</span> `))
+	}
+	context.check(context.Encoder.WriteRaw(html.EscapeCharData(signal)))
+	context.check(context.Encoder.WriteEnd("div"))
+
+	context.check(context.Encoder.WriteStart("div", attr("class", "hazardcontent")))
+	if symbols.Len() > 0 {
+		context.check(context.Encoder.WriteStart("div", attr("class", "hazardsymbols")))
+		context.check(context.Encoder.WriteRaw(symbols.String()))
+		context.check(context.Encoder.WriteEnd("div"))
+	}
+	context.check(context.Encoder.WriteRaw(panels.String()))
+	context.check(context.Encoder.WriteEnd("div"))
+
+	context.check(context.Encoder.WriteEnd("div"))
+	return err
+}
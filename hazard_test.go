@@ -0,0 +1,104 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHazardStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		hazard  string
+		want    []string
+		notwant []string
+	}{
+		{
+			name: "danger",
+			hazard: `<hazardstatement type="danger">
+				<messagepanel><typeofhazard>Voltage</typeofhazard><howtoavoid>Unplug</howtoavoid></messagepanel>
+				<hazardsymbol href="shock.png"/>
+			</hazardstatement>`,
+			want: []string{
+				`<div class="hazardstatement hazardstatement-danger">`,
+				`<div class="hazardsignal"><span class="hazardalert" aria-hidden="true">&#x26a0;</span> DANGER</div>`,
+				`<div class="hazardcontent"><div class="hazardsymbols"><img`,
+				`<div class="messagepanel"><div class="typeofhazard">Voltage</div><div class="howtoavoid">Unplug</div></div>`,
+			},
+			notwant: []string{`type=`},
+		},
+		{
+			name:    "default type",
+			hazard:  `<hazardstatement><messagepanel><typeofhazard>Hot</typeofhazard></messagepanel></hazardstatement>`,
+			want:    []string{`hazardstatement-caution`, `</span> CAUTION</div>`},
+			notwant: []string{`hazardsymbols`},
+		},
+		{
+			name:    "notice",
+			hazard:  `<hazardstatement type="notice"><messagepanel><typeofhazard>Data loss</typeofhazard></messagepanel></hazardstatement>`,
+			want:    []string{`<div class="hazardsignal">NOTICE</div>`},
+			notwant: []string{`hazardalert`},
+		},
+		{
+			name:    "other type",
+			hazard:  `<hazardstatement type="other" othertype="biohazard"><messagepanel><typeofhazard>Samples</typeofhazard></messagepanel></hazardstatement>`,
+			want:    []string{`hazardstatement-biohazard`, `<div class="hazardsignal">BIOHAZARD</div>`},
+			notwant: []string{`othertype=`},
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"shock.png": testPNG(t, 10, 10),
+			"t.dita":    `<topic id="t"><title>T</title><body>` + test.hazard + `</body></topic>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.name, want, output)
+			}
+		}
+		for _, notwant := range test.notwant {
+			if strings.Contains(output, notwant) {
+				t.Errorf("%s: contains %q:\n%s", test.name, notwant, output)
+			}
+		}
+	}
+}
+
+func TestDefinitionLists(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want string
+	}{
+		{
+			name: "dl",
+			list: `<dl><dlentry><dt>Term</dt><dd>Definition</dd></dlentry></dl>`,
+			want: `<dl><div class="dlentry"><dt class="dlterm">Term</dt><dd>Definition</dd></div></dl>`,
+		},
+		{
+			name: "dlhead",
+			list: `<dl><dlhead><dthd>Terms</dthd><ddhd>Definitions</ddhd></dlhead><dlentry><dt>Term</dt><dd>Definition</dd></dlentry></dl>`,
+			want: `<dl><div class="dlhead"><dt class="dthd">Terms</dt><dd class="ddhd">Definitions</dd></div>`,
+		},
+		{
+			name: "parml",
+			list: `<parml><plentry><pt>name</pt><pd>The name</pd></plentry></parml>`,
+			want: `<dl class="parml"><div class="plentry"><dt class="pt">name</dt><dd class="pd">The name</dd></div></dl>`,
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"t.dita":    `<reference id="t"><title>T</title><refbody><section>` + test.list + `</section></refbody></reference>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		if !strings.Contains(output, test.want) {
+			t.Errorf("%s: missing %q:\n%s", test.name, test.want, output)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// DefaultHeadingLevel is the heading level of topic titles.
+// Earlier versions of dita2html always output the topic title as h3
+// and section titles as h2.
+const DefaultHeadingLevel = 1
+
+// isSectionLike checks whether start is a container whose title is
+// one level below the enclosing title, e.g. section or example
+func isSectionLike(start *xml.StartElement) bool {
+	switch start.Name.Local {
+	case "section", "example":
+		return true
+	}
+
+	class := getAttr(start, "class")
+	if !IsDITAClass(class) {
+		class = DefaultClass[start.Name.Local]
+	}
+	for _, ancestor := range ClassAncestry(class) {
+		if ancestor == "section" || ancestor == "example" {
+			return true
+		}
+	}
+	return false
+}
+
+// Heading returns the heading tag for a title at the current nesting,
+// e.g. "h2" for section titles when HeadingLevel is 1
+func (context *Context) Heading() string {
+	depth := context.sectionDepth
+	if depth < 1 {
+		depth = 1
+	}
+	return HeadingTag(context.HeadingLevel + depth)
+}
+
+// TopicHeading returns the heading tag for the topic title
+func (context *Context) TopicHeading() string {
+	return HeadingTag(context.HeadingLevel)
+}
+
+// HeadingTag returns the heading tag for level, clamped to h1-h6
+func HeadingTag(level int) string {
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	return "h" + strconv.Itoa(level)
+}
+
+// HandleTitle outputs title as a heading of the enclosing section,
+// titles of figures and tables are handled by their containers
+func HandleTitle(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	start.Name.Local = context.Heading()
+	setAttr(&start, "class", "sectiontitle")
+	return context.EmitWithChildren(dec, start)
+}
+
+// HandleNestedTopic outputs a topic nested in another topic as a div,
+// the title is one level below the title of the enclosing topic
+func HandleNestedTopic(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	context.HeadingLevel++
+	sectionDepth := context.sectionDepth
+	context.sectionDepth = 0
+	defer func() {
+		context.HeadingLevel--
+		context.sectionDepth = sectionDepth
+	}()
+
+	div := xml.StartElement{Name: xml.Name{Local: "div"}}
+	setAttr(&div, "class", "topic")
+	setAttr(&div, "id", getAttr(&start, "id"))
+	context.check(context.Encoder.Encode(div))
+	defer func() { context.check(context.Encoder.WriteEnd("div")) }()
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			if context.ShouldSkip(token) {
+				dec.Skip()
+				continue
+			}
+
+			name := context.Rules.RuleName(&token)
+			switch {
+			case name == "title" || name == "shortdesc":
+				tag, class := context.TopicHeading(), "topictitle"
+				if name == "shortdesc" {
+					tag, class = "p", "synopsis"
+				}
+				context.check(context.Encoder.WriteStart(tag, attr("class", class)))
+				err := context.Recurse(dec)
+				context.check(context.Encoder.WriteEnd(tag))
+				if err != nil {
+					return err
+				}
+				continue
+			case name == "prolog" || name == "titlealts" || name == "related-links":
+				dec.Skip()
+				continue
+			case IsBodyTag(name):
+				inherited := context.Conditions
+				context.Conditions, _ = context.ElementConditions(inherited, name, token.Attr)
+				err := context.Recurse(dec)
+				context.Conditions = inherited
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := context.Handle(dec, token); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNestedTopicHeadings(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+		"t.dita": `<topic id="t"><title>Root</title>
+			<body>
+				<section><title>Root section</title></section>
+				<settings><setting><settingname>Name</settingname><settingdesc>Desc</settingdesc></setting></settings>
+			</body>
+			<concept id="nested"><title>Nested <b>topic</b></title>
+				<shortdesc>Short</shortdesc>
+				<conbody><section><title>Nested section</title></section></conbody>
+				<topic id="deeper"><title>Deeper</title><body><p>Text</p></body></topic>
+			</concept>
+		</topic>`,
+	}, "t.dita")
+
+	output := context.Output.String()
+	tests := []string{
+		`<h2 class="sectiontitle">Root section</h2>`,
+		`<h2>Name</h2>`,
+		`<div class="topic" id="nested"><h2 class="topictitle">Nested <strong>topic</strong></h2>`,
+		`<p class="synopsis">Short</p>`,
+		`<h3 class="sectiontitle">Nested section</h3>`,
+		`<div class="topic" id="deeper"><h3 class="topictitle">Deeper</h3>`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q:\n%s", want, output)
+		}
+	}
+}
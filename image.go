@@ -0,0 +1,305 @@
+package ditaconvert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/html"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// imageAttributes are translated by HandleImage and not copied to the output
+var imageAttributes = []string{
+	"href", "keyref", "placement", "width", "height",
+	"scale", "scalefit", "align", "alt", "longdescref",
+}
+
+// HandleImage outputs image as img, translating size, scaling and alignment.
+// The alternative text is taken from the alt child or the alt attribute.
+func HandleImage(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	alt, hasAlt := getAttr(&start, "alt"), false
+	longdesc := getAttr(&start, "longdescref")
+
+	// alt and longdescref are the only content of image
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, end := token.(xml.EndElement); end {
+			break
+		}
+
+		child, isStart := token.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+		if context.ShouldSkip(child) {
+			dec.Skip()
+			continue
+		}
+		switch context.Rules.RuleName(&child) {
+		case "alt":
+			text, err := html.XMLText(dec)
+			if err != nil {
+				return err
+			}
+			if !hasAlt {
+				alt = ""
+			}
+			alt += text
+			hasAlt = true
+		case "longdescref":
+			longdesc = getAttr(&child, "href")
+			dec.Skip()
+		default:
+			dec.Skip()
+		}
+	}
+
+	// keyref takes precedence, href is the fallback for undefined keys
+	href := getAttr(&start, "href")
+	if keyref := getAttr(&start, "keyref"); keyref != "" {
+		if keyhref, ok := context.keyHref(keyref); ok {
+			href = keyhref
+		} else if href == "" {
+			context.errorf("keydef missing for %v", keyref)
+		}
+	}
+	intrinsicWidth, intrinsicHeight, probed := context.ImageSize(href)
+
+	img := xml.StartElement{Name: xml.Name{Local: "img"}}
+	src := context.ImageURL(href)
+	setAttr(&img, "src", src)
+	// an empty alt marks the image as decorative
+	img.Attr = append(img.Attr, attr("alt", strings.Join(strings.Fields(alt), " ")))
+
+	var style []string
+	length := func(name string) (css string, px int) {
+		value := getAttr(&start, name)
+		css, px, ok := parseImageLength(value)
+		if !ok && strings.TrimSpace(value) != "" {
+			context.errorf("invalid image %s %q", name, value)
+		}
+		return css, px
+	}
+	width, widthpx := length("width")
+	height, heightpx := length("height")
+
+	// missing dimensions are taken from the image, keeping the aspect ratio
+	if probed && intrinsicWidth > 0 && intrinsicHeight > 0 {
+		switch {
+		case width == "" && height == "":
+			widthpx, heightpx = intrinsicWidth, intrinsicHeight
+		case widthpx > 0 && height == "":
+			heightpx = (widthpx*intrinsicHeight + intrinsicWidth/2) / intrinsicWidth
+		case heightpx > 0 && width == "":
+			widthpx = (heightpx*intrinsicWidth + intrinsicHeight/2) / intrinsicHeight
+		}
+	}
+
+	if value := strings.TrimSpace(getAttr(&start, "scale")); value != "" {
+		scale, err := strconv.Atoi(value)
+		if err != nil || scale <= 0 {
+			context.errorf("invalid image scale %q", value)
+		} else {
+			switch {
+			case widthpx > 0 || heightpx > 0:
+				widthpx, heightpx = widthpx*scale/100, heightpx*scale/100
+			case width == "" && height == "":
+				style = append(style, "zoom: "+value+"%;")
+			}
+		}
+	}
+	if widthpx > 0 {
+		setAttr(&img, "width", strconv.Itoa(widthpx))
+	} else if width != "" {
+		style = append(style, "width: "+width+";")
+	}
+	if heightpx > 0 {
+		setAttr(&img, "height", strconv.Itoa(heightpx))
+	} else if height != "" {
+		style = append(style, "height: "+height+";")
+	}
+
+	// inlined images have no variants
+	if context.ImageVariants != nil && probed && !strings.HasPrefix(src, "data:") && !isExternalURL(href) {
+		variants := context.ImageVariants(context.asset("image", href), intrinsicWidth, intrinsicHeight)
+		if len(variants) > 0 {
+			var srcset []string
+			for _, variant := range variants {
+				srcset = append(srcset, html.NormalizeURL(context.OutputURL(variant.Output))+" "+strconv.Itoa(variant.Width)+"w")
+			}
+			srcset = append(srcset, html.NormalizeURL(src)+" "+strconv.Itoa(intrinsicWidth)+"w")
+			setAttr(&img, "srcset", strings.Join(srcset, ", "))
+
+			sizes := "100vw"
+			if width == "" || widthpx > 0 {
+				display := widthpx
+				if display <= 0 {
+					display = intrinsicWidth
+				}
+				sizes = fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", display, display)
+			}
+			setAttr(&img, "sizes", sizes)
+		}
+	}
+
+	class := getAttr(&start, "class")
+	if getAttr(&start, "scalefit") == "yes" {
+		class = strings.TrimSpace(class + " scalefit")
+		style = append(style, "max-width: 100%; height: auto;")
+	}
+	setAttr(&img, "class", class)
+	setAttr(&img, "style", strings.Join(style, " "))
+
+	for _, a := range start.Attr {
+		if a.Name.Local == "class" || a.Name.Local == "style" {
+			continue
+		}
+		if !isImageAttribute(a.Name.Local) && a.Value != "" {
+			img.Attr = append(img.Attr, a)
+		}
+	}
+
+	placement := getAttr(&start, "placement")
+	if placement == "break" {
+		wrapclass := "image"
+		if align := strings.ToLower(strings.TrimSpace(getAttr(&start, "align"))); align != "" {
+			wrapclass += " align-" + align
+		}
+		context.check(context.Encoder.WriteStart("p", attr("class", wrapclass)))
+	}
+
+	context.check(context.Encoder.Encode(img))
+	context.check(context.Encoder.WriteEnd("img"))
+
+	if longdesc != "" {
+		href, title := longdesc, ""
+		ext := strings.ToLower(path.Ext(trimLink(longdesc)))
+		switch {
+		case isExternalURL(longdesc):
+		case ext == ".dita" || ext == ".xml":
+			href, title, _, _ = context.ResolveLinkInfo(longdesc)
+		default:
+			href = context.AssetURL("download", longdesc)
+		}
+		if title == "" {
+			title = "Image description"
+		}
+		context.check(context.Encoder.WriteStart("a",
+			attr("class", "longdescref"),
+			attr("href", href)))
+		context.check(context.Encoder.WriteRaw(html.EscapeCharData(title)))
+		context.check(context.Encoder.WriteEnd("a"))
+	}
+
+	if placement == "break" {
+		context.check(context.Encoder.WriteEnd("p"))
+	}
+	return nil
+}
+
+// keyHref returns the target of the key in keyref relative to the topic
+func (context *Context) keyHref(keyref string) (href string, ok bool) {
+	key := strings.SplitN(keyref, "/", 2)[0]
+	target, ok := context.Index.KeyDef[key]
+	if !ok {
+		return "", false
+	}
+
+	href, err := filepath.Rel(filepath.FromSlash(path.Dir(context.DecodingPath)), filepath.FromSlash(target))
+	if err != nil {
+		return target, true
+	}
+	return filepath.ToSlash(href), true
+}
+
+type imageSize struct {
+	Width, Height int
+	OK            bool
+}
+
+// ImageSize returns the intrinsic size of image href read through the
+// file system, ok is false for external, missing or unsupported images
+func (context *Context) ImageSize(href string) (width, height int, ok bool) {
+	if href == "" || isExternalURL(href) {
+		return 0, 0, false
+	}
+
+	name := path.Join(path.Dir(context.DecodingPath), trimLink(href))
+	index := context.Index
+	if size, probed := index.imageSizes[CanonicalPath(name)]; probed {
+		return size.Width, size.Height, size.OK
+	}
+	if index.imageSizes == nil {
+		index.imageSizes = make(map[string]imageSize)
+	}
+
+	var size imageSize
+	// missing files are reported when the url is resolved
+	if data, _, err := index.ReadFile(name); err == nil {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			size = imageSize{config.Width, config.Height, true}
+		}
+	}
+	index.imageSizes[CanonicalPath(name)] = size
+	return size.Width, size.Height, size.OK
+}
+
+// parseImageLength parses a DITA length, unitless values are pixels.
+// px is set when the length is in pixels.
+func parseImageLength(value string) (css string, px int, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", 0, false
+	}
+
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return !('0' <= r && r <= '9' || r == '.')
+	})
+	if i < 0 {
+		i = len(value)
+	}
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil || number < 0 {
+		return "", 0, false
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(value[i:]))
+	switch unit {
+	case "", "px":
+		return value[:i] + "px", int(number + 0.5), true
+	case "pt", "pc", "in", "cm", "mm", "em", "%":
+		return value[:i] + unit, 0, true
+	}
+	return "", 0, false
+}
+
+func isImageAttribute(name string) bool {
+	for _, attr := range imageAttributes {
+		if attr == name {
+			return true
+		}
+	}
+	return false
+}
+
+// trimLink removes the fragment from link
+func trimLink(link string) string {
+	name, _ := SplitLink(link)
+	return name
+}
@@ -0,0 +1,35 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImageKeyRef(t *testing.T) {
+	tests := []struct {
+		topic string
+		image string
+		src   string
+	}{
+		{"t.dita", `<image keyref="logo"/>`, `src="images/logo.png"`},
+		{"sub/t.dita", `<image keyref="logo"/>`, `src="../images/logo.png"`},
+		{"t.dita", `<image keyref="logo" href="other.png"/>`, `src="images/logo.png"`},
+		{"t.dita", `<image keyref="undefined" href="images/logo.png"/>`, `src="images/logo.png"`},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map>
+				<keydef keys="logo" href="images/logo.png" format="png"/>
+				<topicref href="` + test.topic + `"/>
+			</map>`,
+			"images/logo.png": testPNG(t, 10, 10),
+			test.topic:        `<topic id="t"><title>T</title><body><p>` + test.image + `</p></body></topic>`,
+		}, test.topic)
+
+		output := context.Output.String()
+		if !strings.Contains(output, test.src) {
+			t.Errorf("%s: missing %s:\n%s", test.image, test.src, output)
+		}
+	}
+}
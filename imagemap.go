@@ -3,10 +3,12 @@ package ditaconvert
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
 	"path"
 	"strconv"
 	"strings"
 
+	"github.com/raintreeinc/ditaconvert/html"
 	"github.com/raintreeinc/ditaconvert/imagemap"
 )
 
@@ -27,19 +29,59 @@ func ConvertImageMap(context *Context, dec *xml.Decoder, start xml.StartElement)
 		return nil
 	}
 
-	ext := strings.Trim(strings.ToLower(path.Ext(name)), ".")
-	if ext == "" {
-		context.errorf("invalid image link: %s", href)
+	content, err := imagemap.FromXML(context.ImageURL(href), data, m.Area)
+	if err != nil {
+		context.check(err)
 		return nil
 	}
-	if ext == "jpg" {
-		ext = "jpeg"
+
+	// the alt element is used instead of the alt attribute
+	alt := m.Image.Alt
+	if m.Image.AltText != nil {
+		alt, err = html.XMLText(xml.NewDecoder(strings.NewReader(m.Image.AltText.Content)))
+		if err != nil && err != io.EOF {
+			return err
+		}
 	}
+	alt = strings.Join(strings.Fields(alt), " ")
 
-	content, err := imagemap.FromXML(ext, data, m.Area)
-	if err != nil {
-		context.check(err)
-		return nil
+	// resolve area links the same way as xref
+	type link struct{ href, title, synopsis string }
+	links := make([]link, len(content.Areas))
+	for i, area := range content.Areas {
+		href := area.Href
+		if href == "" && area.KeyRef != "" {
+			href = context.ResolveKeyLink(area.KeyRef)
+		}
+		// areas without a link are not output
+		if href == "" {
+			continue
+		}
+
+		switch area.Scope {
+		case "external", "peer":
+			links[i] = link{href: href}
+		default:
+			links[i].href, links[i].title, links[i].synopsis, _ = context.ResolveLinkInfo(href)
+		}
+	}
+
+	// label returns the accessible name of the area
+	label := func(i int) string {
+		if area := content.Areas[i]; area.Alt != "" {
+			return area.Alt
+		}
+		if links[i].title != "" {
+			return links[i].title
+		}
+		return links[i].href
+	}
+	// tooltip returns the hover text of the area
+	tooltip := func(i int) string {
+		if links[i].synopsis != "" {
+			return links[i].synopsis
+		}
+		return label(i)
 	}
 
 	emitStart := func(tag string, attrs ...xml.Attr) {
@@ -48,15 +90,44 @@ func ConvertImageMap(context *Context, dec *xml.Decoder, start xml.StartElement)
 	emitEnd := func(tag string) {
 		context.check(context.Encoder.WriteEnd(tag))
 	}
+	emitText := func(text string) {
+		context.check(context.Encoder.Encode(xml.CharData(text)))
+	}
 
 	emitStart("div", attr("class", "imagemap"))
 	defer emitEnd("div")
 
+	// the style is shared by all imagemaps in the topic
+	if !context.imagemapStyled {
+		emitStart("style")
+		context.check(context.Encoder.WriteRaw(imagemapStyle))
+		emitEnd("style")
+		context.imagemapStyled = true
+	}
+
 	defer func() {
 		emitStart("div",
 			attr("class", "imagemap-tip"))
 		context.Encoder.WriteRaw("Click any highlighted region for details.")
 		emitEnd("div")
+
+		// the links are also listed for keyboard and screen reader users
+		emitStart("ul", attr("class", "imagemap-links"))
+		for i := range content.Areas {
+			if links[i].href == "" {
+				continue
+			}
+			emitStart("li")
+			attrs := []xml.Attr{attr("href", links[i].href)}
+			if links[i].synopsis != "" {
+				attrs = append(attrs, attr("title", links[i].synopsis))
+			}
+			emitStart("a", attrs...)
+			emitText(label(i))
+			emitEnd("a")
+			emitEnd("li")
+		}
+		emitEnd("ul")
 	}()
 
 	emitStart("div",
@@ -66,6 +137,7 @@ func ConvertImageMap(context *Context, dec *xml.Decoder, start xml.StartElement)
 
 	emitStart("img",
 		attr("src", content.Image),
+		attr("alt", alt),
 		attr("style", "width: 100%; max-width:"+strconv.Itoa(content.Size.X)+"px;"),
 	)
 	emitEnd("img")
@@ -80,10 +152,16 @@ func ConvertImageMap(context *Context, dec *xml.Decoder, start xml.StartElement)
 	)
 	defer emitEnd("div")
 
-	for _, area := range content.Areas {
+	// rect and default areas are positioned boxes
+	emitBox := func(i int) {
+		area := content.Areas[i]
+		if links[i].href == "" {
+			return
+		}
 		emitStart("a",
-			attr("title", area.Alt),
-			attr("alt", area.Alt),
+			attr("class", "imagemap-area"),
+			attr("title", tooltip(i)),
+			attr("aria-label", label(i)),
 
 			attr("style",
 				fmt.Sprintf("position: absolute; display: block; left: %.3f%%; top: %.3f%%; width: %.3f%%; height: %.3f%%;",
@@ -92,14 +170,87 @@ func ConvertImageMap(context *Context, dec *xml.Decoder, start xml.StartElement)
 					float64(area.Max.X-area.Min.X)*100/float64(content.Size.X),
 					float64(area.Max.Y-area.Min.Y)*100/float64(content.Size.Y),
 				)),
-			attr("href", area.Href),
+			attr("href", links[i].href),
 		)
 		emitEnd("a")
 	}
 
+	// default areas cover the whole image, they are below other areas
+	for i, area := range content.Areas {
+		if area.Shape == "default" {
+			emitBox(i)
+		}
+	}
+
+	// circles and polygons are drawn in an svg scaled with the image
+	shapes := false
+	for i, area := range content.Areas {
+		shapes = shapes || links[i].href != "" && (area.Shape == "circle" || area.Shape == "poly")
+	}
+	if shapes {
+		emitStart("svg",
+			attr("class", "imagemap-shapes"),
+			attr("xmlns", "http://www.w3.org/2000/svg"),
+			attr("viewBox", fmt.Sprintf("0 0 %d %d", content.Size.X, content.Size.Y)),
+			attr("preserveAspectRatio", "none"),
+			attr("style", "position: absolute; left: 0; top: 0; width: 100%; height: 100%; pointer-events: none;"),
+		)
+		for i, area := range content.Areas {
+			var tag string
+			var attrs []xml.Attr
+			switch area.Shape {
+			case "circle":
+				tag = "circle"
+				attrs = []xml.Attr{
+					attr("cx", strconv.Itoa(area.Center.X)),
+					attr("cy", strconv.Itoa(area.Center.Y)),
+					attr("r", strconv.Itoa(area.Radius)),
+				}
+			case "poly":
+				var points []string
+				for _, p := range area.Points {
+					points = append(points, strconv.Itoa(p.X)+","+strconv.Itoa(p.Y))
+				}
+				tag = "polygon"
+				attrs = []xml.Attr{attr("points", strings.Join(points, " "))}
+			default:
+				continue
+			}
+			if links[i].href == "" {
+				continue
+			}
+			attrs = append(attrs, attr("fill", "transparent"))
+
+			emitStart("a",
+				attr("class", "imagemap-area"),
+				attr("href", links[i].href),
+				attr("tabindex", "0"),
+				attr("aria-label", label(i)),
+				attr("style", "pointer-events: auto;"),
+			)
+			emitStart("title")
+			emitText(tooltip(i))
+			emitEnd("title")
+			emitStart(tag, attrs...)
+			emitEnd(tag)
+			emitEnd("a")
+		}
+		emitEnd("svg")
+	}
+
+	for i, area := range content.Areas {
+		if area.Shape == "rect" {
+			emitBox(i)
+		}
+	}
+
 	return nil
 }
 
+// imagemapStyle makes the focused hotspot visible
+const imagemapStyle = `.imagemap-area:focus { outline: 2px solid #1a73e8; outline-offset: 1px; }` +
+	`.imagemap-shapes .imagemap-area:focus > * { stroke: #1a73e8; stroke-width: 3px; vector-effect: non-scaling-stroke; }`
+
 func attr(name string, value string) xml.Attr {
 	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
 }
@@ -2,18 +2,18 @@ package imagemap
 
 import (
 	"bytes"
-	"encoding/base64"
 	"errors"
 	"image"
 	"strconv"
 	"strings"
 
+	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 )
 
 type Content struct {
-	Image string `json:"image"` // in base64
+	Image string `json:"image"` // url or data url
 	Areas []Area `json:"areas"`
 	Size  Point  `json:"size"`
 }
@@ -24,57 +24,103 @@ type Point struct {
 }
 
 type Area struct {
-	Href string `json:"href"`
-	Alt  string `json:"alt,omitempty"`
-	Min  Point  `json:"min"`
-	Max  Point  `json:"max"`
+	Href   string `json:"href"`
+	KeyRef string `json:"keyref,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+	Alt    string `json:"alt,omitempty"`
+	Shape  string `json:"shape"`
+
+	// bounding box of the shape
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+
+	// circle
+	Center Point `json:"center,omitempty"`
+	Radius int   `json:"radius,omitempty"`
+
+	// poly
+	Points []Point `json:"points,omitempty"`
 }
 
 type XMLArea struct {
 	Shape  string `xml:"shape"`
 	Coords string `xml:"coords"`
 	XRef   struct {
-		Href string `xml:"href,attr"`
-		Alt  string `xml:",chardata"`
+		Href   string `xml:"href,attr"`
+		KeyRef string `xml:"keyref,attr"`
+		Scope  string `xml:"scope,attr"`
+		Alt    string `xml:",chardata"`
 	} `xml:"xref"`
 }
 
 type XML struct {
 	Image struct {
 		Href string `xml:"href,attr"`
+		Alt  string `xml:"alt,attr"`
+		// AltText is the content of the alt element, which
+		// is used instead of the alt attribute
+		AltText *struct {
+			Content string `xml:",innerxml"`
+		} `xml:"alt"`
 	} `xml:"image"`
 	Area []XMLArea `xml:"area"`
 }
 
-func FromXML(mime string, data []byte, areas []XMLArea) (content *Content, err error) {
-	content = &Content{}
+// FromXML computes the areas of the image map, data is used to determine the
+// image size and url is the image source in the output
+func FromXML(url string, data []byte, areas []XMLArea) (content *Content, err error) {
+	content = &Content{Image: url}
 
 	for _, area := range areas {
-		switch area.Shape {
-		case "rect":
-			tokens := strings.Split(area.Coords, ",")
-			if len(tokens) != 4 {
-				return nil, errors.New("invalid imagemap coords \"" + area.Coords + "\"")
-			}
+		shape := strings.ToLower(strings.TrimSpace(area.Shape))
+		if shape == "" {
+			shape = "rect"
+		}
 
-			x0, err0 := strconv.Atoi(strings.TrimSpace(tokens[0]))
-			y0, err1 := strconv.Atoi(strings.TrimSpace(tokens[1]))
-			x1, err2 := strconv.Atoi(strings.TrimSpace(tokens[2]))
-			y1, err3 := strconv.Atoi(strings.TrimSpace(tokens[3]))
+		invalid := errors.New("invalid imagemap coords \"" + area.Coords + "\" for shape \"" + area.Shape + "\"")
+		coords, err := parseCoords(area.Coords)
+		if err != nil {
+			return nil, invalid
+		}
 
-			if err0 != nil || err1 != nil || err2 != nil || err3 != nil {
-				return nil, errors.New("invalid imagemap coords \"" + area.Coords + "\"")
-			}
+		result := Area{
+			Href:   area.XRef.Href,
+			KeyRef: area.XRef.KeyRef,
+			Scope:  area.XRef.Scope,
+			Alt:    strings.TrimSpace(area.XRef.Alt),
+			Shape:  shape,
+		}
 
-			content.Areas = append(content.Areas, Area{
-				Href: area.XRef.Href,
-				Alt:  area.XRef.Alt,
-				Min:  Point{x0, y0},
-				Max:  Point{x1, y1},
-			})
+		switch shape {
+		case "rect":
+			if len(coords) != 4 {
+				return nil, invalid
+			}
+			result.Min = Point{coords[0], coords[1]}
+			result.Max = Point{coords[2], coords[3]}
+		case "circle":
+			if len(coords) != 3 {
+				return nil, invalid
+			}
+			result.Center = Point{coords[0], coords[1]}
+			result.Radius = coords[2]
+			result.Min = Point{coords[0] - coords[2], coords[1] - coords[2]}
+			result.Max = Point{coords[0] + coords[2], coords[1] + coords[2]}
+		case "poly":
+			if len(coords) < 6 || len(coords)%2 != 0 {
+				return nil, invalid
+			}
+			for i := 0; i < len(coords); i += 2 {
+				result.Points = append(result.Points, Point{coords[i], coords[i+1]})
+			}
+			result.Min, result.Max = bounds(result.Points)
+		case "default":
+			// the size is known after decoding the image
 		default:
 			return nil, errors.New("unhandled imagemap shape \"" + area.Shape + "\"")
 		}
+
+		content.Areas = append(content.Areas, result)
 	}
 
 	img, _, err := image.Decode(bytes.NewReader(data))
@@ -84,8 +130,48 @@ func FromXML(mime string, data []byte, areas []XMLArea) (content *Content, err e
 	content.Size.X = img.Bounds().Dx()
 	content.Size.Y = img.Bounds().Dy()
 
-	encoded := base64.StdEncoding.EncodeToString(data)
-	content.Image = "data:image/" + mime + ";base64," + encoded
+	// default covers the whole image
+	for i := range content.Areas {
+		if content.Areas[i].Shape == "default" {
+			content.Areas[i].Max = content.Size
+		}
+	}
 
 	return content, nil
 }
+
+// parseCoords parses comma or space separated integers
+func parseCoords(coords string) ([]int, error) {
+	var values []int
+	tokens := strings.FieldsFunc(coords, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	for _, token := range tokens {
+		value, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// bounds returns the bounding box of points
+func bounds(points []Point) (min, max Point) {
+	min, max = points[0], points[0]
+	for _, p := range points[1:] {
+		if p.X < min.X {
+			min.X = p.X
+		}
+		if p.Y < min.Y {
+			min.Y = p.Y
+		}
+		if p.X > max.X {
+			max.X = p.X
+		}
+		if p.Y > max.Y {
+			max.Y = p.Y
+		}
+	}
+	return min, max
+}
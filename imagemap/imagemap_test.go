@@ -0,0 +1,65 @@
+package imagemap
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func TestFromXMLShapes(t *testing.T) {
+	var data bytes.Buffer
+	if err := png.Encode(&data, image.NewGray(image.Rect(0, 0, 100, 50))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		shape  string
+		coords string
+		area   Area
+		err    bool
+	}{
+		{shape: "", coords: "1,2,3,4", area: Area{Shape: "rect", Min: Point{1, 2}, Max: Point{3, 4}}},
+		{shape: "RECT", coords: "1 2 3 4", area: Area{Shape: "rect", Min: Point{1, 2}, Max: Point{3, 4}}},
+		{shape: "circle", coords: "20,20,5", area: Area{
+			Shape:  "circle",
+			Center: Point{20, 20}, Radius: 5,
+			Min: Point{15, 15}, Max: Point{25, 25},
+		}},
+		{shape: "poly", coords: "10,0, 20,30, 0,10", area: Area{
+			Shape:  "poly",
+			Points: []Point{{10, 0}, {20, 30}, {0, 10}},
+			Min:    Point{0, 0}, Max: Point{20, 30},
+		}},
+		{shape: "default", coords: "", area: Area{Shape: "default", Max: Point{100, 50}}},
+		{shape: "rect", coords: "1,2,3", err: true},
+		{shape: "circle", coords: "1,2", err: true},
+		{shape: "poly", coords: "1,2,3,4", err: true},
+		{shape: "poly", coords: "1,2,3,4,5,6,7", err: true},
+		{shape: "rect", coords: "a,b,c,d", err: true},
+		{shape: "star", coords: "1,2,3,4", err: true},
+	}
+
+	for _, test := range tests {
+		areas := []XMLArea{{Shape: test.shape, Coords: test.coords}}
+		content, err := FromXML("map.png", data.Bytes(), areas)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s %q: expected an error", test.shape, test.coords)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", test.shape, test.coords, err)
+			continue
+		}
+
+		if content.Size != (Point{100, 50}) {
+			t.Errorf("%s %q: got size %v", test.shape, test.coords, content.Size)
+		}
+		if !reflect.DeepEqual(content.Areas, []Area{test.area}) {
+			t.Errorf("%s %q: got %+v, expected %+v", test.shape, test.coords, content.Areas, test.area)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ditaconvert
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testPNG returns an encoded png image of the specified size
+func testPNG(t *testing.T, width, height int) string {
+	var out bytes.Buffer
+	if err := png.Encode(&out, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestImageMapAccessibility(t *testing.T) {
+	imagemap := func(image string) string {
+		return `<imagemap>` + image + `
+			<area><shape>rect</shape><coords>0,0,10,10</coords><xref href="t.dita#t/p">Linked</xref></area>
+			<area><shape>circle</shape><coords>20,20,5</coords></area>
+		</imagemap>`
+	}
+
+	tests := []struct {
+		image string
+		alt   string
+	}{
+		{`<image href="map.png" alt="Attribute"/>`, `alt="Attribute"`},
+		{`<image href="map.png" alt="Attribute"><alt>Element <b>text</b></alt></image>`, `alt="Element text"`},
+		{`<image href="map.png"/>`, `alt=""`},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"map.png":   testPNG(t, 40, 40),
+			"t.dita": `<topic id="t"><title>T</title><body><p id="p">Text</p>` +
+				imagemap(test.image) + imagemap(test.image) +
+				`</body></topic>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		if !strings.Contains(output, test.alt) {
+			t.Errorf("%s: missing %s:\n%s", test.image, test.alt, output)
+		}
+		if n := strings.Count(output, `aria-label="Linked"`); n != 2 {
+			t.Errorf("%s: got %d linked areas, expected 2:\n%s", test.image, n, output)
+		}
+		if n := strings.Count(output, "<style>"); n != 1 {
+			t.Errorf("%s: got %d style elements, expected 1", test.image, n)
+		}
+		if strings.Contains(output, `href=""`) || strings.Contains(output, "<svg") {
+			t.Errorf("%s: contains an area without a link:\n%s", test.image, output)
+		}
+	}
+}
+
+func TestImageMapShapes(t *testing.T) {
+	tests := []struct {
+		area    string
+		want    []string
+		notwant []string
+	}{
+		{
+			area: `<area><shape>circle</shape><coords>20,20,5</coords><xref href="t.dita#t/p">Circle</xref></area>`,
+			want: []string{
+				`<svg class="imagemap-shapes" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 40 40"`,
+				`<a class="imagemap-area" href="t.html#t/p" tabindex="0" aria-label="Circle"`,
+				`<circle cx="20" cy="20" r="5" fill="transparent">`,
+			},
+		},
+		{
+			area: `<area><shape>poly</shape><coords>0,0 10,0 10,10</coords><xref href="t.dita#t/p">Triangle</xref></area>`,
+			want: []string{`<polygon points="0,0 10,0 10,10" fill="transparent">`},
+		},
+		{
+			area:    `<area><shape>default</shape><coords></coords><xref href="t.dita#t/p">Whole</xref></area>`,
+			want:    []string{`left: 0.000%; top: 0.000%; width: 100.000%; height: 100.000%;`},
+			notwant: []string{`<svg`},
+		},
+		{
+			area:    `<area><shape>rect</shape><coords>0,0,20,10</coords><xref href="t.dita#t/p">Box</xref></area>`,
+			want:    []string{`left: 0.000%; top: 0.000%; width: 50.000%; height: 25.000%;`},
+			notwant: []string{`<svg`},
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"map.png":   testPNG(t, 40, 40),
+			"t.dita": `<topic id="t"><title>T</title><body><p id="p">Text</p>
+				<imagemap><image href="map.png" alt="Map"/>` + test.area + `</imagemap>
+			</body></topic>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.area, want, output)
+			}
+		}
+		for _, notwant := range test.notwant {
+			if strings.Contains(output, notwant) {
+				t.Errorf("%s: contains %q:\n%s", test.area, notwant, output)
+			}
+		}
+	}
+}
@@ -18,6 +18,28 @@ type Index struct {
 
 	Nav *Entry
 
+	// back-of-book index, collected during conversion
+	Terms *IndexTerm
+
+	// controlled values and DITAVAL filtering, optional
+	Scheme *SubjectScheme
+	Filter *Filter
+
+	// elements without conversion rules, name --> usage
+	Unknown map[string]*UnknownElement
+
+	// booklist placeholders (toc, indexlist, glossarylist ...)
+	// which should be generated
+	BookLists []*Entry
+
+	// Numbering of titled figures and tables
+	Numbering Numbering
+	// topic cpath --> labels, computed on first use
+	labels map[string][]Label
+
+	// image path --> intrinsic size, probed on first use
+	imageSizes map[string]imageSize
+
 	Errors []error
 }
 
@@ -31,13 +53,21 @@ type Topic struct {
 	Links               []Links
 	RelatedLinksCreated bool
 
+	// metadata and conditions cascaded from maps, a topic is converted
+	// once, so it only gets the conditions shared by all its references
+	Metadata   Metadata
+	Conditions Conditions
+	references int
+
 	Raw      []byte
 	Modified time.Time
 	Original *dita.Topic
 }
 
 type Map struct {
-	Path    string
+	Path string
+	// Title is the map title or the main book title
+	Title   string
 	Entries []*Entry
 	Node    *dita.MapNode
 }
@@ -46,6 +76,18 @@ type Entry struct {
 	Title string
 	Type  string
 
+	// bookmap element kind, e.g. chapter, appendix, indexlist
+	Kind string
+	// chapter or appendix number, e.g. "3" or "B"
+	Number string
+
+	// overrides from topicmeta
+	LinkText  string
+	ShortDesc string
+	Metadata  Metadata
+
+	Conditions Conditions
+
 	CollType  dita.CollectionType
 	Linking   dita.Linking
 	TOC       bool
@@ -65,6 +107,9 @@ func NewIndex(fs FileSystem) *Index {
 			TOC:     true,
 		},
 
+		Terms:   &IndexTerm{},
+		Unknown: make(map[string]*UnknownElement),
+
 		KeyDef: make(map[string]string),
 
 		Maps:   make(map[string]*Map),
@@ -72,6 +117,16 @@ func NewIndex(fs FileSystem) *Index {
 	}
 }
 
+// HasBookList checks whether a booklist placeholder of kind was loaded
+func (index *Index) HasBookList(kind string) bool {
+	for _, entry := range index.BookLists {
+		if entry.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
 func (index *Index) check(err error) bool {
 	if err != nil {
 		index.Errors = append(index.Errors, err)
@@ -89,8 +144,14 @@ func (index *Index) LoadMap(name string) {
 		CollType: dita.Unordered,
 		Linking:  dita.NormalLinking,
 		TOC:      true,
+		counters: &bookCounters{},
 	}
 
 	entries := context.LoadMap(path.Base(name))
 	index.Nav.Children = append(index.Nav.Children, entries...)
+
+	// the book title is used for the whole publication
+	if m, ok := index.Maps[CanonicalPath(path.Clean(name))]; ok && m.Node.XMLName.Local == "bookmap" && m.Title != "" {
+		index.Nav.Title = m.Title
+	}
 }
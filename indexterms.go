@@ -0,0 +1,261 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+)
+
+// IndexTerm is a single node in the back-of-book index
+type IndexTerm struct {
+	Term   string
+	SortAs string
+
+	Targets []IndexTarget
+	See     []string
+	SeeAlso []string
+
+	Children []*IndexTerm
+}
+
+// IndexTarget is a location where an indexterm was defined,
+// Anchor is empty for indexterms in the prolog
+type IndexTarget struct {
+	Topic  *Topic
+	Anchor string
+}
+
+// IndexGroup contains all top-level terms starting with the same letter
+type IndexGroup struct {
+	Letter string
+	Terms  []*IndexTerm
+}
+
+func (term *IndexTerm) SortKey() string {
+	if term.SortAs != "" {
+		return strings.ToLower(term.SortAs)
+	}
+	return strings.ToLower(term.Term)
+}
+
+// Child finds or creates a subterm with the specified name
+func (term *IndexTerm) Child(name string) *IndexTerm {
+	for _, child := range term.Children {
+		if child.Term == name {
+			return child
+		}
+	}
+	child := &IndexTerm{Term: name}
+	term.Children = append(term.Children, child)
+	return child
+}
+
+type termsByKey []*IndexTerm
+
+func (xs termsByKey) Len() int      { return len(xs) }
+func (xs termsByKey) Swap(i, j int) { xs[i], xs[j] = xs[j], xs[i] }
+func (xs termsByKey) Less(i, j int) bool {
+	a, b := xs[i].SortKey(), xs[j].SortKey()
+	if a == b {
+		return xs[i].Term < xs[j].Term
+	}
+	return a < b
+}
+
+// Sort sorts all subterms recursively
+func (term *IndexTerm) Sort() {
+	sort.Sort(termsByKey(term.Children))
+	for _, child := range term.Children {
+		child.Sort()
+	}
+}
+
+// IndexGroups returns sorted top-level terms grouped by their first letter
+func (index *Index) IndexGroups() []IndexGroup {
+	index.Terms.Sort()
+
+	var groups []IndexGroup
+	for _, term := range index.Terms.Children {
+		letter := "#"
+		for _, r := range term.SortKey() {
+			if unicode.IsLetter(r) {
+				letter = string(unicode.ToUpper(r))
+			}
+			break
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].Letter != letter {
+			groups = append(groups, IndexGroup{Letter: letter})
+		}
+		group := &groups[len(groups)-1]
+		group.Terms = append(group.Terms, term)
+	}
+	return groups
+}
+
+// indexTermXML is the parsed content of a single <indexterm>
+type indexTermXML struct {
+	Term     string
+	SortAs   string
+	See      []string
+	SeeAlso  []string
+	Children []*indexTermXML
+}
+
+// addIndexTerm adds the parsed indexterm under parent, the deepest
+// terms without index-see will refer to target.
+func (index *Index) addIndexTerm(parent *IndexTerm, x *indexTermXML, target IndexTarget) {
+	if x.Term == "" {
+		return
+	}
+
+	term := parent.Child(x.Term)
+	if x.SortAs != "" {
+		term.SortAs = x.SortAs
+	}
+	term.See = appendUnique(term.See, x.See...)
+	term.SeeAlso = appendUnique(term.SeeAlso, x.SeeAlso...)
+
+	for _, child := range x.Children {
+		index.addIndexTerm(term, child, target)
+	}
+
+	if len(x.Children) == 0 && len(x.See) == 0 {
+		term.Targets = append(term.Targets, target)
+	}
+}
+
+func appendUnique(xs []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, x := range xs {
+			if x == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			xs = append(xs, value)
+		}
+	}
+	return xs
+}
+
+// decodeIndexTerm reads the content of <indexterm>, <index-see> or <index-see-also>
+func decodeIndexTerm(dec *xml.Decoder) (*indexTermXML, error) {
+	term := &indexTermXML{}
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return term, err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			term.Term = strings.Join(strings.Fields(term.Term), " ")
+			return term, nil
+		case xml.CharData:
+			term.Term += string(token)
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "indexterm":
+				child, err := decodeIndexTerm(dec)
+				if err != nil {
+					return term, err
+				}
+				term.Children = append(term.Children, child)
+			case "index-sort-as":
+				text, err := decodeIndexTerm(dec)
+				if err != nil {
+					return term, err
+				}
+				term.SortAs = text.Term
+			case "index-see", "index-see-also":
+				ref, err := decodeIndexTerm(dec)
+				if err != nil {
+					return term, err
+				}
+				if token.Name.Local == "index-see" {
+					term.See = append(term.See, ref.Path())
+				} else {
+					term.SeeAlso = append(term.SeeAlso, ref.Path())
+				}
+			default:
+				// inline content, e.g. <b> or <keyword>
+				sub, err := decodeIndexTerm(dec)
+				if err != nil {
+					return term, err
+				}
+				term.Term += sub.Term
+			}
+		}
+	}
+	term.Term = strings.Join(strings.Fields(term.Term), " ")
+	return term, nil
+}
+
+// Path returns the term with the first nested subterms, separated by commas
+func (x *indexTermXML) Path() string {
+	if len(x.Children) == 0 {
+		return x.Term
+	}
+	return x.Term + ", " + x.Children[0].Path()
+}
+
+// HandleIndexTerm collects the indexterm into Index and emits an anchor for it
+func HandleIndexTerm(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	term, err := decodeIndexTerm(dec)
+	if err != nil {
+		return err
+	}
+	if term.Term == "" {
+		context.errorf("empty indexterm")
+		return nil
+	}
+
+	anchor := context.NewAnchor("indexterm")
+
+	context.check(context.Encoder.WriteStart("a",
+		attr("id", anchor),
+		attr("class", "indexterm")))
+	context.check(context.Encoder.WriteEnd("a"))
+
+	context.addIndexTerm(term, anchor)
+	return nil
+}
+
+// addPrologIndexTerm collects an indexterm from the prolog,
+// the term refers to the whole topic
+func (context *Context) addPrologIndexTerm(x dita.IndexTerm) error {
+	start := xml.StartElement{Name: xml.Name{Local: "indexterm"}, Attr: x.Attrs}
+	if context.ShouldSkip(start) {
+		return nil
+	}
+
+	term, err := decodeIndexTerm(xml.NewDecoder(strings.NewReader(x.Content)))
+	if err != nil {
+		return err
+	}
+	if term.Term == "" {
+		context.errorf("empty indexterm")
+		return nil
+	}
+
+	context.addIndexTerm(term, "")
+	return nil
+}
+
+func (context *Context) addIndexTerm(term *indexTermXML, anchor string) {
+	context.IndexTerms = append(context.IndexTerms, term.Path())
+	context.Index.addIndexTerm(context.Index.Terms, term, IndexTarget{
+		Topic:  context.Topic,
+		Anchor: anchor,
+	})
+}
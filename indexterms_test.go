@@ -0,0 +1,51 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIndexTermsCollected(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap":      `<map><topicref href="t.dita"/></map>`,
+		"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+		"t.dita": `<topic id="t"><title>T</title>
+			<prolog><metadata><keywords>
+				<keyword>not a term</keyword>
+				<indexterm>Printing<indexterm>setup</indexterm></indexterm>
+				<indexterm product="old">Excluded</indexterm>
+			</keywords></metadata></prolog>
+			<body><p><indexterm>Scanning</indexterm>Text</p></body>
+		</topic>`,
+	}, "t.dita")
+
+	tests := []struct {
+		path   []string
+		anchor string
+	}{
+		{[]string{"Printing", "setup"}, ""},
+		{[]string{"Scanning"}, "indexterm-1"},
+	}
+	for _, test := range tests {
+		term := context.Index.Terms
+		for _, name := range test.path {
+			term = term.Child(name)
+		}
+		if len(term.Targets) != 1 {
+			t.Errorf("%v: got %d targets, expected 1", test.path, len(term.Targets))
+			continue
+		}
+		if target := term.Targets[0]; target.Topic != context.Topic || target.Anchor != test.anchor {
+			t.Errorf("%v: got target %v#%v, expected t.dita#%v", test.path, target.Topic.Path, target.Anchor, test.anchor)
+		}
+	}
+
+	for _, term := range context.Index.Terms.Children {
+		if term.Term == "Excluded" || term.Term == "not a term" {
+			t.Errorf("contains %q", term.Term)
+		}
+	}
+	if got := strings.Join(context.IndexTerms, ";"); got != "Printing, setup;Scanning" {
+		t.Errorf("got IndexTerms %q", got)
+	}
+}
@@ -15,10 +15,17 @@ type Link struct {
 	Scope string
 	Href  string
 
+	// overrides from map topicmeta
+	LinkText  string
+	ShortDesc string
+
 	Selector string
 }
 
 func (link *Link) FinalTitle() string {
+	if link.LinkText != "" {
+		return link.LinkText
+	}
 	if link.Title != "" {
 		return link.Title
 	}
@@ -31,6 +38,17 @@ func (link *Link) FinalTitle() string {
 	return ""
 }
 
+// Synopsis returns the description of the linked topic
+func (link *Link) Synopsis() string {
+	if link.ShortDesc != "" {
+		return link.ShortDesc
+	}
+	if link.Topic != nil {
+		return link.Topic.Synopsis
+	}
+	return ""
+}
+
 type Links struct {
 	CollType   dita.CollectionType
 	Parent     *Link
@@ -46,6 +64,9 @@ func (e *Entry) asLink() *Link {
 		Type:  e.Type,
 		Scope: "",
 		Href:  "",
+
+		LinkText:  e.LinkText,
+		ShortDesc: e.ShortDesc,
 	}
 }
 
@@ -17,6 +17,12 @@ type MapContext struct {
 	CollType dita.CollectionType
 	Linking  dita.Linking
 	TOC      bool
+	Metadata Metadata
+
+	// inherited conditional processing attributes
+	Conditions Conditions
+
+	counters *bookCounters
 }
 
 func (context MapContext) LoadMap(filename string) []*Entry {
@@ -32,13 +38,19 @@ func (context MapContext) LoadMap(filename string) []*Entry {
 		return nil
 	}
 
-	m := &Map{Node: &dita.MapNode{}}
+	m := &Map{Path: name, Node: &dita.MapNode{}}
 	if err := xml.Unmarshal(data, m.Node); err != nil {
 		context.check(fmt.Errorf("failed to unmarshal map %s: %v", name, err))
 		return nil
 	}
+	m.Title = mapTitle(m.Node)
 	context.Maps[cname] = m
 
+	if m.Node.XMLName.Local == "subjectScheme" {
+		context.Index.addSubjectScheme(m.Node)
+		return nil
+	}
+
 	context.Dir = path.Dir(name)
 	m.Entries = context.ProcessNode(m.Node)
 
@@ -83,17 +95,49 @@ func (context MapContext) LoadTopic(filename string) *Topic {
 	if top.Title == "" {
 		top.Title = topic.Title
 	}
+	if topic.XMLName.Local == "glossentry" {
+		top.Title = glossTerm(topic)
+		top.ShortTitle = top.Title
+		top.Synopsis, _ = topic.GlossDef.Text()
+	}
 
 	context.Topics[cname] = top
 	return top
 }
 
+// addReference records the conditions of a topicref to topic
+func (topic *Topic) addReference(conditions Conditions) {
+	if topic.references == 0 {
+		topic.Conditions = conditions
+	} else {
+		topic.Conditions = topic.Conditions.Shared(conditions)
+	}
+	topic.references++
+}
+
 func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 	if node == nil {
 		panic("invalid node passed as argument")
 	}
 
-	if node.Format != "" || !isWebAudience(node.Audience, node.Print, node.DeliveryTarget) || isResourceOnly(node.ProcessRole) {
+	// subject schemes are usually resource-only
+	if node.Type == "subjectScheme" && node.Href != "" {
+		context.LoadMap(node.Href)
+		return nil
+	}
+
+	// keydefs may refer to images and other non-DITA resources
+	if (node.Format != "" && node.Format != "ditamap" && node.XMLName.Local != "keydef") || !isWebAudience(node.Audience, node.Print, node.DeliveryTarget) || isResourceOnly(node.ProcessRole) {
+		return nil
+	}
+
+	conditions := ConditionsFromAttrs(node.Attrs())
+	if err := context.Index.Scheme.Validate(conditions); err != nil {
+		context.check(fmt.Errorf("map %s <%s href=%q>: %v", context.Dir, node.XMLName.Local, node.Href, err))
+	}
+
+	context.Conditions = context.Conditions.Cascade(conditions, node.Cascade != "nomerge")
+	if !context.Index.IsIncluded(conditions) || !context.Index.IsIncluded(context.Conditions) {
 		return nil
 	}
 
@@ -112,7 +156,15 @@ func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 		context.Linking = node.Linking
 	}
 
-	if node.XMLName.Local == "topicgroup" || node.XMLName.Local == "map" {
+	if node.TopicMeta != nil {
+		context.Metadata = context.Metadata.Merge(MetadataFromTopicMeta(node.TopicMeta))
+	}
+
+	if node.XMLName.Local == "bookmeta" || node.XMLName.Local == "booktitle" {
+		return nil
+	}
+
+	if node.XMLName.Local == "topicgroup" || node.XMLName.Local == "map" || isBookContainer(node) {
 		var entries []*Entry
 		context.TOC = isChildTOC(context.TOC, node.TOC)
 		for _, child := range node.Children {
@@ -126,9 +178,28 @@ func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 		return entries
 	}
 
-	if node.XMLName.Local == "mapref" {
+	if node.XMLName.Local == "mapref" || node.Format == "ditamap" {
 		context.TOC = isChildTOC(context.TOC, node.TOC)
-		return context.LoadMap(node.Href)
+		entries := context.LoadMap(node.Href)
+		if bookKinds[node.XMLName.Local] {
+			entries = context.BookSubmap(node, entries)
+		}
+		return entries
+	}
+
+	if isBookListPlaceholder(node) {
+		entry := &Entry{
+			Title:    node.NavTitle,
+			Kind:     node.XMLName.Local,
+			CollType: context.CollType,
+			Linking:  dita.NoLinking,
+			TOC:      isChildTOC(context.TOC, node.TOC),
+		}
+		if entry.Title == "" {
+			entry.Title = bookListTitles[node.XMLName.Local]
+		}
+		context.Index.BookLists = append(context.Index.BookLists, entry)
+		return []*Entry{entry}
 	}
 
 	if node.XMLName.Local == "reltable" {
@@ -141,7 +212,19 @@ func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 	}
 
 	if node.XMLName.Local == "keydef" {
-		context.Index.KeyDef[node.Keys] = path.Join(context.Dir, node.Href)
+		context.AddKeys(node)
+		// keys may refer to glossentries, which are used via keyref,
+		// a missing format defaults to the extension like in DITA
+		format := node.Format
+		if format == "" {
+			switch strings.ToLower(path.Ext(node.Href)) {
+			case ".dita", ".xml":
+				format = "dita"
+			}
+		}
+		if node.Href != "" && format == "dita" {
+			context.LoadTopic(node.Href)
+		}
 		return nil
 	}
 
@@ -152,16 +235,38 @@ func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 		CollType:  context.CollType,
 		Linking:   context.Linking,
 		TOC:       isChildTOC(context.TOC, node.TOC),
+		Metadata:  context.Metadata,
+
+		Conditions: context.Conditions,
+	}
+	if meta := node.TopicMeta; meta != nil {
+		if navtitle := strings.TrimSpace(meta.NavTitle); navtitle != "" {
+			entry.Title = navtitle
+		}
+		entry.LinkText = strings.TrimSpace(meta.LinkText)
+		entry.ShortDesc, _ = meta.ShortDesc.Text()
+		entry.ShortDesc = strings.TrimSpace(entry.ShortDesc)
 	}
 	if entry.Title == "" {
 		entry.Title = node.Title
 	}
+	if bookKinds[node.XMLName.Local] {
+		context.NumberBookEntry(entry, node.XMLName.Local)
+	}
+
+	if node.Keys != "" && node.Href != "" {
+		context.AddKeys(node)
+	}
 
 	if node.Href != "" {
 		entry.Topic = context.LoadTopic(node.Href)
 		if entry.Title == "" && entry.Topic != nil && !entry.LockTitle {
 			entry.Title = entry.Topic.Title
 		}
+		if entry.Topic != nil {
+			entry.Topic.Metadata = entry.Topic.Metadata.Merge(entry.Metadata)
+			entry.Topic.addReference(entry.Conditions)
+		}
 	}
 
 	context.Entry = entry
@@ -179,6 +284,17 @@ func (context MapContext) ProcessNode(node *dita.MapNode) []*Entry {
 	return []*Entry{entry}
 }
 
+// AddKeys defines all keys of node to refer to node.Href
+func (context MapContext) AddKeys(node *dita.MapNode) {
+	for _, key := range strings.Fields(node.Keys) {
+		if _, defined := context.Index.KeyDef[key]; defined {
+			// first definition wins
+			continue
+		}
+		context.Index.KeyDef[key] = path.Join(context.Dir, node.Href)
+	}
+}
+
 func (context MapContext) ProcessRelRow(node *dita.MapNode) {
 	if !isWebAudience(node.Audience, node.Print, node.DeliveryTarget) {
 		return
@@ -208,12 +324,22 @@ func (context MapContext) ProcessRelRow(node *dita.MapNode) {
 }
 
 func isWebAudience(audience string, print, deliveryTarget string) bool {
-	return !(audience == "html" ||
-		audience == "print" ||
+	return !(isNonWebAudience(audience) ||
 		print == "printonly" ||
 		(deliveryTarget != "" && !strings.Contains(" "+deliveryTarget+" ", " KB ")))
 }
 
+// isNonWebAudience checks whether all audience values exclude web
+func isNonWebAudience(audience string) bool {
+	values := strings.Fields(audience)
+	for _, value := range values {
+		if value != "html" && value != "print" {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
 func isChildTOC(parenttoc bool, childtoc string) bool {
 	if childtoc == "" {
 		return parenttoc
@@ -0,0 +1,79 @@
+package ditaconvert
+
+import (
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+)
+
+// Metadata is map-level metadata which cascades into the referenced topics
+type Metadata struct {
+	Keywords  []string
+	OtherMeta []dita.OtherMeta
+	Audience  []dita.Audience
+}
+
+func MetadataFromTopicMeta(meta *dita.TopicMeta) Metadata {
+	if meta == nil {
+		return Metadata{}
+	}
+
+	var keywords []string
+	for _, keyword := range meta.Keywords.Terms() {
+		keywords = append(keywords, strings.TrimSpace(keyword))
+	}
+
+	return Metadata{
+		Keywords:  keywords,
+		OtherMeta: meta.OtherMeta,
+		Audience:  meta.Audience,
+	}
+}
+
+func (meta Metadata) IsEmpty() bool {
+	return len(meta.Keywords) == 0 && len(meta.OtherMeta) == 0 && len(meta.Audience) == 0
+}
+
+// Merge returns a new Metadata containing values from both, without duplicates
+func (meta Metadata) Merge(other Metadata) Metadata {
+	if other.IsEmpty() {
+		return meta
+	}
+
+	result := Metadata{}
+	result.Keywords = appendUnique(append([]string{}, meta.Keywords...), other.Keywords...)
+
+	result.OtherMeta = append([]dita.OtherMeta{}, meta.OtherMeta...)
+	for _, x := range other.OtherMeta {
+		if !containsOtherMeta(result.OtherMeta, x) {
+			result.OtherMeta = append(result.OtherMeta, x)
+		}
+	}
+
+	result.Audience = append([]dita.Audience{}, meta.Audience...)
+	for _, x := range other.Audience {
+		if !containsAudience(result.Audience, x) {
+			result.Audience = append(result.Audience, x)
+		}
+	}
+
+	return result
+}
+
+func containsOtherMeta(xs []dita.OtherMeta, v dita.OtherMeta) bool {
+	for _, x := range xs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAudience(xs []dita.Audience, v dita.Audience) bool {
+	for _, x := range xs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
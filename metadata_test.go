@@ -0,0 +1,30 @@
+package ditaconvert
+
+import "testing"
+
+func TestEntryTitles(t *testing.T) {
+	index := NewIndex(VFS{
+		"m.ditamap": `<map>
+			<topicref href="t.dita" navtitle="Attribute"/>
+			<topicref href="t.dita" navtitle="Locked" locktitle="yes"/>
+			<topicref href="t.dita"><topicmeta><navtitle>Topicmeta</navtitle></topicmeta></topicref>
+			<topicref href="t.dita"/>
+		</map>`,
+		"t.dita": `<topic id="t"><title>Topic title</title></topic>`,
+	})
+	index.LoadMap("m.ditamap")
+	for _, err := range index.Errors {
+		t.Errorf("loading map: %v", err)
+	}
+
+	expected := []string{"Attribute", "Locked", "Topicmeta", "Topic title"}
+	entries := index.Nav.Children
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, expected %d", len(entries), len(expected))
+	}
+	for i, title := range expected {
+		if entries[i].Title != title {
+			t.Errorf("%d: got title %q, expected %q", i, entries[i].Title, title)
+		}
+	}
+}
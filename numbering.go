@@ -0,0 +1,211 @@
+package ditaconvert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Numbering specifies how titled figures and tables are numbered
+type Numbering string
+
+const (
+	// NumberingNone does not number figures and tables
+	NumberingNone Numbering = ""
+	// NumberingTopic restarts numbering in each topic
+	NumberingTopic Numbering = "topic"
+	// NumberingPublication numbers continuously in map order
+	NumberingPublication Numbering = "publication"
+)
+
+// LabelNames contains the label prefixes of numbered elements
+var LabelNames = map[string]string{
+	"fig":   "Figure",
+	"table": "Table",
+}
+
+// Label is the generated number of a titled figure or table
+type Label struct {
+	Kind   string
+	ID     string
+	Number int
+}
+
+// String returns the label text, e.g. "Figure 3"
+func (label Label) String() string {
+	return LabelNames[label.Kind] + " " + strconv.Itoa(label.Number)
+}
+
+// TopicLabels returns labels of figures and tables in topic in
+// document order. Labels are computed for all topics on first use.
+//
+// Figures and tables included with conref are not numbered.
+func (index *Index) TopicLabels(topic *Topic) []Label {
+	if index.Numbering == NumberingNone || topic == nil {
+		return nil
+	}
+	if index.labels == nil {
+		index.numberLabels()
+	}
+	return index.labels[CanonicalPath(topic.Path)]
+}
+
+func (index *Index) numberLabels() {
+	index.labels = make(map[string][]Label)
+
+	counts := make(map[string]int)
+	number := func(topic *Topic) {
+		name := CanonicalPath(topic.Path)
+		if _, done := index.labels[name]; done {
+			return
+		}
+		if index.Numbering == NumberingTopic {
+			counts = make(map[string]int)
+		}
+		index.labels[name] = index.scanLabels(topic.Raw, topic.Conditions, counts)
+	}
+
+	// map order first
+	var walk func(entry *Entry)
+	walk = func(entry *Entry) {
+		if entry.Topic != nil {
+			number(entry.Topic)
+		}
+		for _, child := range entry.Children {
+			walk(child)
+		}
+	}
+	walk(index.Nav)
+
+	// topics not in the navigation, e.g. only referenced by keys
+	var rest []string
+	for name := range index.Topics {
+		if _, done := index.labels[name]; !done {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		number(index.Topics[name])
+	}
+}
+
+// scanLabels finds titled figures and tables in data, which are not
+// excluded by the filter
+func (index *Index) scanLabels(data []byte, conditions Conditions, counts map[string]int) []Label {
+	var labels []Label
+
+	var element struct {
+		ID    string    `xml:"id,attr"`
+		Title *struct{} `xml:"title"`
+		Inner []byte    `xml:",innerxml"`
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				index.check(err)
+			}
+			return labels
+		}
+
+		start, isStart := token.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+
+		own := ConditionsFromAttrs(start.Attr)
+		cascaded := conditions.Cascade(own, getAttr(&start, "cascade") != "nomerge")
+		if !index.IsIncluded(own) || !index.IsIncluded(cascaded) {
+			dec.Skip()
+			continue
+		}
+
+		kind := start.Name.Local
+		if _, numbered := LabelNames[kind]; !numbered {
+			continue
+		}
+
+		element.ID, element.Title, element.Inner = "", nil, nil
+		if err := dec.DecodeElement(&element, &start); err != nil {
+			index.check(err)
+			return labels
+		}
+		if element.Title != nil {
+			counts[kind]++
+			labels = append(labels, Label{
+				Kind:   kind,
+				ID:     element.ID,
+				Number: counts[kind],
+			})
+		}
+
+		// figures inside tables and vice versa
+		labels = append(labels, index.scanLabels(element.Inner, cascaded, counts)...)
+	}
+}
+
+// NextLabel returns the label for the next titled figure or table
+// in the converted topic, elements with an id are matched by id.
+// Elements included with conref are not numbered.
+func (context *Context) NextLabel(kind, id string) string {
+	if context.conrefDepth > 0 {
+		return ""
+	}
+	if context.labelCount == nil {
+		context.labelCount = make(map[string]int)
+	}
+	context.labelCount[kind]++
+	nth := context.labelCount[kind]
+
+	labels := context.Index.TopicLabels(context.Topic)
+	if id != "" {
+		for _, label := range labels {
+			if label.Kind == kind && strings.EqualFold(label.ID, id) {
+				return label.String()
+			}
+		}
+	}
+
+	for _, label := range labels {
+		if label.Kind == kind {
+			nth--
+			if nth == 0 {
+				return label.String()
+			}
+		}
+	}
+	return ""
+}
+
+// ResolveLabel returns the label of the figure or table targeted by url,
+// e.g. "Figure 3", or "" when the target is not numbered
+func (context *Context) ResolveLabel(url string) string {
+	url, selector := SplitLink(url)
+	if selector == "" {
+		return ""
+	}
+
+	name := context.DecodingPath
+	if url != "" {
+		name = path.Join(path.Dir(context.DecodingPath), url)
+	}
+	topic, ok := context.Index.Topics[CanonicalPath(name)]
+	if !ok {
+		return ""
+	}
+
+	id := selector[strings.LastIndex(selector, "/")+1:]
+	for _, label := range context.Index.TopicLabels(topic) {
+		if strings.EqualFold(label.ID, id) {
+			return label.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberingSkipsConref(t *testing.T) {
+	files := VFS{
+		"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+		"shared.dita": `<topic id="shared"><title>Shared</title><body>
+			<fig id="f"><title>Shared figure</title></fig>
+		</body></topic>`,
+		"t.dita": `<topic id="t"><title>T</title><body>
+			<fig conref="shared.dita#shared/f"/>
+			<fig><title>Own figure</title></fig>
+		</body></topic>`,
+	}
+
+	index := NewIndex(files)
+	index.Numbering = NumberingTopic
+	index.LoadMap("m.ditamap")
+	context := NewConversion(index, index.Topics["t.dita"])
+	if err := context.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := context.Output.String()
+	shared := strings.Index(output, "Shared figure")
+	own := strings.Index(output, "Own figure")
+	label := strings.Index(output, "Figure 1")
+	if shared < 0 || own < 0 || label < 0 {
+		t.Fatalf("missing figure or label:\n%s", output)
+	}
+	if label < shared || label > own || strings.Count(output, "figlabel") != 1 {
+		t.Errorf("expected only the own figure to be labeled:\n%s", output)
+	}
+}
@@ -0,0 +1,135 @@
+package ditaconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+// ParseRules parses declarative rules from JSON, e.g.
+//
+//	{
+//		"rename": {"keystroke": {"name": "b", "class": "key"}},
+//		"skip": {"settinghead": true},
+//		"unwrap": {"tgroup": true},
+//		"wrap": {"faq": {"name": "div", "class": "faq"}},
+//		"add-attributes": {"ui-item": {"role": "group"}},
+//		"drop-attributes": {"*": ["outputclass"]},
+//		"unknown": "element"
+//	}
+//
+// The unknown policy is one of "as-is", "element", "unwrap" or "error".
+func ParseRules(data []byte) (*Rules, error) {
+	rules := &Rules{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %v", err)
+	}
+
+	for tag, renaming := range rules.Rename {
+		if renaming.Name == "" {
+			return nil, fmt.Errorf("invalid rules: rename %q is missing name", tag)
+		}
+	}
+	switch rules.Unknown {
+	case "", UnknownAsIs, UnknownAsElement, UnknownUnwrap, UnknownError:
+	default:
+		return nil, fmt.Errorf("invalid rules: unknown policy %q", rules.Unknown)
+	}
+
+	for tag, wrap := range rules.Wrap {
+		if wrap.Name == "" {
+			return nil, fmt.Errorf("invalid rules: wrap %q is missing name", tag)
+		}
+	}
+
+	return rules, nil
+}
+
+// Merge adds rules from other, overriding the existing ones.
+// Renaming an element replaces its existing custom processor,
+// unless other defines a custom processor for it as well.
+func (rules *Rules) Merge(other *Rules) {
+	if other.CustomResolveLink != nil {
+		rules.CustomResolveLink = other.CustomResolveLink
+	}
+
+	if other.Unknown != "" {
+		rules.Unknown = other.Unknown
+	}
+
+	if rules.Rename == nil {
+		rules.Rename = make(map[string]Renaming)
+	}
+	for tag, renaming := range other.Rename {
+		rules.Rename[tag] = renaming
+		if _, custom := other.Custom[tag]; !custom {
+			delete(rules.Custom, tag)
+		}
+	}
+
+	if rules.Skip == nil {
+		rules.Skip = make(map[string]bool)
+	}
+	for tag, skip := range other.Skip {
+		rules.Skip[tag] = skip
+	}
+
+	if rules.Unwrap == nil {
+		rules.Unwrap = make(map[string]bool)
+	}
+	for tag, unwrap := range other.Unwrap {
+		rules.Unwrap[tag] = unwrap
+	}
+
+	if rules.Custom == nil {
+		rules.Custom = make(map[string]TokenProcessor)
+	}
+	for tag, process := range other.Custom {
+		rules.Custom[tag] = process
+	}
+
+	if rules.Wrap == nil {
+		rules.Wrap = make(map[string]Renaming)
+	}
+	for tag, wrap := range other.Wrap {
+		rules.Wrap[tag] = wrap
+	}
+
+	if rules.AddAttributes == nil {
+		rules.AddAttributes = make(map[string]map[string]string)
+	}
+	for tag, attrs := range other.AddAttributes {
+		merged := make(map[string]string)
+		for name, value := range rules.AddAttributes[tag] {
+			merged[name] = value
+		}
+		for name, value := range attrs {
+			merged[name] = value
+		}
+		rules.AddAttributes[tag] = merged
+	}
+
+	if rules.DropAttributes == nil {
+		rules.DropAttributes = make(map[string][]string)
+	}
+	for tag, names := range other.DropAttributes {
+		rules.DropAttributes[tag] = appendUnique(rules.DropAttributes[tag], names...)
+	}
+}
+
+// ModifyAttributes applies AddAttributes and DropAttributes to start,
+// first the rules for all elements and then the rules for each of tags
+func (rules *Rules) ModifyAttributes(start *xml.StartElement, tags ...string) {
+	for _, tag := range append([]string{"*"}, tags...) {
+		for _, name := range rules.DropAttributes[tag] {
+			setAttr(start, name, "")
+		}
+		for name, value := range rules.AddAttributes[tag] {
+			setAttr(start, name, value)
+		}
+	}
+}
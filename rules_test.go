@@ -0,0 +1,107 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeUnknownPolicy(t *testing.T) {
+	tests := []struct {
+		rules    string
+		expected UnknownPolicy
+	}{
+		{`{"unknown": "as-is"}`, UnknownAsIs},
+		{`{"unknown": "unwrap"}`, UnknownUnwrap},
+		// rules without a policy keep the existing one
+		{`{}`, UnknownAsElement},
+	}
+
+	for _, test := range tests {
+		custom, err := ParseRules([]byte(test.rules))
+		if err != nil {
+			t.Errorf("%s: %v", test.rules, err)
+			continue
+		}
+
+		rules := NewDefaultRules()
+		rules.Merge(custom)
+		if rules.Unknown != test.expected {
+			t.Errorf("%s: got unknown policy %q, expected %q", test.rules, rules.Unknown, test.expected)
+		}
+	}
+
+	if _, err := ParseRules([]byte(`{"unknown": "other"}`)); err == nil {
+		t.Errorf("expected an error for an invalid policy")
+	}
+}
+
+func TestRulesFile(t *testing.T) {
+	tests := []struct {
+		rules   string
+		body    string
+		want    []string
+		unknown string
+	}{
+		{
+			rules: `{"add-attributes": {"widget": {"role": "note"}}}`,
+			body:  `<widget>Text</widget>`,
+			want:  []string{`<widget role="note">Text</widget>`},
+		},
+		{
+			rules: `{"drop-attributes": {"widget": ["outputclass"]}}`,
+			body:  `<widget outputclass="x">Text</widget>`,
+			want:  []string{`<widget>Text</widget>`},
+		},
+		{
+			rules: `{"add-attributes": {"apiname": {"translate": "no"}}}`,
+			body:  `<p><apiname>X</apiname></p>`,
+			want:  []string{`class="keyword"`, `translate="no"`},
+		},
+		{
+			rules: `{"rename": {"note": {"name": "aside", "class": "callout"}}}`,
+			body:  `<note type="tip">Text</note>`,
+			want:  []string{`<aside class="callout" type="tip">Text</aside>`},
+		},
+		{
+			rules:   `{}`,
+			body:    `<widget>Text</widget>`,
+			unknown: "widget",
+		},
+	}
+
+	for _, test := range tests {
+		custom, err := ParseRules([]byte(test.rules))
+		if err != nil {
+			t.Errorf("%s: %v", test.rules, err)
+			continue
+		}
+
+		index := NewIndex(VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"t.dita":    `<topic id="t"><title>T</title><body>` + test.body + `</body></topic>`,
+		})
+		index.LoadMap("m.ditamap")
+
+		context := NewConversion(index, index.Topics["t.dita"])
+		context.Rules.Merge(custom)
+		if err := context.Run(); err != nil {
+			t.Errorf("%s: %v", test.rules, err)
+			continue
+		}
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.rules, want, output)
+			}
+		}
+		for name := range index.Unknown {
+			if name != test.unknown {
+				t.Errorf("%s: <%s> reported as unknown", test.rules, name)
+			}
+		}
+		if _, reported := index.Unknown[test.unknown]; test.unknown != "" && !reported {
+			t.Errorf("%s: <%s> not reported as unknown", test.rules, test.unknown)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/dita"
+)
+
+// SubjectScheme contains subject hierarchies and the controlled values of attributes
+type SubjectScheme struct {
+	// all defined subjects
+	Subjects map[string]bool
+	// subject --> parent subject
+	Parent map[string]string
+	// attribute name --> subjects, whose descendants are also allowed
+	Enumeration map[string]map[string]bool
+}
+
+func NewSubjectScheme() *SubjectScheme {
+	return &SubjectScheme{
+		Subjects:    make(map[string]bool),
+		Parent:      make(map[string]string),
+		Enumeration: make(map[string]map[string]bool),
+	}
+}
+
+// LoadSubjectScheme loads subject scheme map from a path relative to FileSystem root
+func (index *Index) LoadSubjectScheme(name string) {
+	data, _, err := index.ReadFile(name)
+	if err != nil {
+		index.check(fmt.Errorf("failed to read subject scheme %s: %v", name, err))
+		return
+	}
+
+	node := &dita.MapNode{}
+	if err := xml.Unmarshal(data, node); err != nil {
+		index.check(fmt.Errorf("failed to unmarshal subject scheme %s: %v", name, err))
+		return
+	}
+
+	index.addSubjectScheme(node)
+}
+
+func (index *Index) addSubjectScheme(node *dita.MapNode) {
+	if index.Scheme == nil {
+		index.Scheme = NewSubjectScheme()
+	}
+	index.Scheme.add(node, "")
+}
+
+func subjectName(node *dita.MapNode) string {
+	if keys := strings.Fields(node.Keys); len(keys) > 0 {
+		return keys[0]
+	}
+	return strings.TrimSpace(node.KeyRef)
+}
+
+func (scheme *SubjectScheme) add(node *dita.MapNode, parent string) {
+	switch node.XMLName.Local {
+	case "enumerationdef":
+		scheme.addEnumeration(node)
+		return
+	case "subjectdef", "defaultSubject":
+		name := subjectName(node)
+		if name != "" {
+			scheme.Subjects[name] = true
+			if parent != "" {
+				scheme.Parent[name] = parent
+			}
+			parent = name
+		}
+	}
+
+	for _, child := range node.Children {
+		scheme.add(child, parent)
+	}
+}
+
+func (scheme *SubjectScheme) addEnumeration(node *dita.MapNode) {
+	var attributes []string
+	var subjects []*dita.MapNode
+	for _, child := range node.Children {
+		switch child.XMLName.Local {
+		case "attributedef":
+			attributes = append(attributes, child.Name)
+		case "subjectdef":
+			subjects = append(subjects, child)
+			// enumerations may define subjects inline
+			scheme.add(child, "")
+		}
+	}
+
+	for _, attribute := range attributes {
+		allowed, ok := scheme.Enumeration[attribute]
+		if !ok {
+			allowed = make(map[string]bool)
+			scheme.Enumeration[attribute] = allowed
+		}
+		for _, subject := range subjects {
+			if name := subjectName(subject); name != "" {
+				allowed[name] = true
+			}
+		}
+	}
+}
+
+// Ancestors returns all parent subjects of value, starting from the closest
+func (scheme *SubjectScheme) Ancestors(value string) []string {
+	var ancestors []string
+	seen := map[string]bool{value: true}
+	for {
+		parent, ok := scheme.Parent[value]
+		if !ok || seen[parent] {
+			return ancestors
+		}
+		seen[parent] = true
+		ancestors = append(ancestors, parent)
+		value = parent
+	}
+}
+
+// IsAllowed checks whether value is permitted for the attribute
+func (scheme *SubjectScheme) IsAllowed(attribute, value string) bool {
+	allowed, bound := scheme.Enumeration[attribute]
+	if !bound {
+		return true
+	}
+	if !scheme.Subjects[value] {
+		return false
+	}
+	if allowed[value] {
+		return true
+	}
+	for _, ancestor := range scheme.Ancestors(value) {
+		if allowed[ancestor] {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks all values in conditions against the scheme
+func (scheme *SubjectScheme) Validate(conditions Conditions) error {
+	if scheme == nil {
+		return nil
+	}
+
+	var invalid []string
+	for attribute := range conditions {
+		for _, value := range conditions.Values(attribute) {
+			if !scheme.IsAllowed(attribute, value) {
+				invalid = append(invalid, attribute+"=\""+value+"\"")
+			}
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+	return fmt.Errorf("values not defined in subject scheme: %s", strings.Join(invalid, ", "))
+}
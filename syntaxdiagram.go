@@ -0,0 +1,309 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// synnode is a syntax diagram element with its children in document order
+type synnode struct {
+	Start    xml.StartElement
+	Kind     string
+	Text     string
+	Children []*synnode
+}
+
+func (node *synnode) attr(name string) string { return getAttr(&node.Start, name) }
+
+// child returns the first child of the specified kind
+func (node *synnode) child(kind string) *synnode {
+	for _, child := range node.Children {
+		if child.Kind == kind {
+			return child
+		}
+	}
+	return nil
+}
+
+// synKinds contains elements with a specific notation in syntax diagrams
+var synKinds = map[string]bool{
+	"title": true, "fragment": true, "fragref": true,
+	"groupseq": true, "groupchoice": true, "groupcomp": true, "synblk": true,
+	"kwd": true, "var": true, "oper": true, "delim": true, "sep": true, "repsep": true,
+	"synnote": true, "synnoteref": true,
+}
+
+// synKind returns the syntax element name of start, specializations
+// use the closest syntax element in the class attribute
+func synKind(start *xml.StartElement) string {
+	if synKinds[start.Name.Local] {
+		return start.Name.Local
+	}
+
+	class := getAttr(start, "class")
+	if !IsDITAClass(class) {
+		class = DefaultClass[start.Name.Local]
+	}
+	for _, ancestor := range ClassAncestry(class) {
+		if synKinds[ancestor] {
+			return ancestor
+		}
+	}
+	return start.Name.Local
+}
+
+// readSynNode reads the element started by start, including its children
+func (context *Context) readSynNode(dec *xml.Decoder, start xml.StartElement) (*synnode, error) {
+	node := &synnode{
+		Start: start,
+		Kind:  synKind(&start),
+	}
+
+	var text []string
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return node, err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			node.Text = strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+			return node, nil
+		case xml.CharData:
+			text = append(text, string(token))
+		case xml.StartElement:
+			children, err := context.readSynChild(dec, token)
+			for _, child := range children {
+				text = append(text, child.Text)
+				node.Children = append(node.Children, child)
+			}
+			if err != nil {
+				return node, err
+			}
+		}
+	}
+
+	node.Text = strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+	return node, nil
+}
+
+// readSynChild reads a child of a syntax element, conditions and
+// conrefs are applied the same way as in Handle
+func (context *Context) readSynChild(dec *xml.Decoder, start xml.StartElement) ([]*synnode, error) {
+	if context.ShouldSkip(start) {
+		dec.Skip()
+		return nil, nil
+	}
+	if IsConref(start) {
+		return context.readSynConref(dec, start)
+	}
+
+	inherited := context.Conditions
+	context.Conditions, _ = context.ElementConditions(inherited, start.Name.Local, start.Attr)
+	defer func() { context.Conditions = inherited }()
+
+	node, err := context.readSynNode(dec, start)
+	return []*synnode{node}, err
+}
+
+// readSynConref reads the elements referenced by conref or conkeyref
+func (context *Context) readSynConref(dec *xml.Decoder, start xml.StartElement) ([]*synnode, error) {
+	dec.Skip()
+
+	subdec, subfirst, endingid, err := context.openConref(start)
+	if err != nil {
+		return nil, err
+	}
+
+	context.conrefDepth++
+	defer func() { context.conrefDepth-- }()
+
+	var nodes []*synnode
+	var subtoken xml.Token = subfirst
+	for {
+		if substart, isStart := subtoken.(xml.StartElement); isStart {
+			children, err := context.readSynChild(subdec, substart)
+			nodes = append(nodes, children...)
+			if err != nil {
+				return nodes, err
+			}
+			if strings.EqualFold(endingid, getAttr(&substart, "id")) {
+				return nodes, nil
+			}
+		}
+
+		if _, isEnd := subtoken.(xml.EndElement); isEnd {
+			return nodes, errors.New("did not find conrefend: " + getAttr(&start, "conrefend"))
+		}
+
+		subtoken, err = subdec.Token()
+		if err != nil {
+			return nodes, err
+		}
+	}
+}
+
+// HandleSyntaxDiagram outputs syntaxdiagram as text using the common notation:
+//
+//	[ optional ]  { choice | choice }  repeated ...  <fragment>
+//
+// Fragments are written on separate lines after the main syntax.
+func HandleSyntaxDiagram(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	diagram, err := context.readSynNode(dec, start)
+	if err != nil {
+		return err
+	}
+
+	figure := xml.StartElement{Name: xml.Name{Local: "figure"}}
+	setAttr(&figure, "class", "syntaxdiagram")
+	setAttr(&figure, "id", getAttr(&start, "id"))
+	context.check(context.Encoder.Encode(figure))
+	defer func() { context.check(context.Encoder.WriteEnd("figure")) }()
+
+	if title := diagram.child("title"); title != nil {
+		context.check(context.Encoder.WriteStart("figcaption"))
+		context.check(context.Encoder.WriteRaw(html.EscapeCharData(title.Text)))
+		context.check(context.Encoder.WriteEnd("figcaption"))
+	}
+
+	var main []string
+	var fragments []*synnode
+	for _, child := range diagram.Children {
+		switch child.Kind {
+		case "title":
+		case "fragment":
+			fragments = append(fragments, child)
+		default:
+			main = append(main, renderSyntax(child))
+		}
+	}
+
+	context.check(context.Encoder.WriteStart("pre", attr("class", "syntax")))
+	context.check(context.Encoder.WriteRaw(strings.Join(main, " ")))
+	for _, fragment := range fragments {
+		context.check(context.Encoder.WriteRaw("\n\n"))
+		context.check(context.Encoder.WriteRaw(renderFragment(fragment)))
+	}
+	context.check(context.Encoder.WriteEnd("pre"))
+
+	return nil
+}
+
+func renderFragment(fragment *synnode) string {
+	name := ""
+	if title := fragment.child("title"); title != nil {
+		name = title.Text
+	}
+
+	var parts []string
+	for _, child := range fragment.Children {
+		if child.Kind != "title" {
+			parts = append(parts, renderSyntax(child))
+		}
+	}
+
+	id := fragment.attr("id")
+	if id == "" {
+		id = fragmentID(name)
+	}
+
+	return `<span class="fragment" id="` + html.EscapeAttribute(id) + `">` +
+		`<span class="fragmenttitle">` + html.EscapeCharData(name) + `:</span>` +
+		"\n  " + strings.Join(parts, " ") + `</span>`
+}
+
+// fragmentID creates an id for fragments without one,
+// fragref-s to such fragments use the fragment title
+func fragmentID(name string) string {
+	return "fragment-" + strings.Join(strings.Fields(strings.ToLower(name)), "-")
+}
+
+func renderSyntax(node *synnode) string {
+	var s string
+	switch node.Kind {
+	case "kwd":
+		s = `<kbd class="kwd">` + html.EscapeCharData(node.Text) + `</kbd>`
+		if node.attr("importance") == "default" {
+			s = "<u>" + s + "</u>"
+		}
+	case "var":
+		s = `<var class="var">` + html.EscapeCharData(node.Text) + `</var>`
+	case "oper", "delim", "sep":
+		s = `<span class="` + node.Kind + `">` + html.EscapeCharData(node.Text) + `</span>`
+	case "fragref":
+		// fragments are in the same diagram, "#topic/fragment" --> "#fragment"
+		_, selector := SplitLink(node.attr("href"))
+		selector = selector[strings.LastIndex(selector, "/")+1:]
+
+		name := node.Text
+		if name == "" {
+			name = selector
+		}
+		href := "#" + selector
+		if selector == "" {
+			href = "#" + fragmentID(name)
+		}
+		s = `<a class="fragref" href="` + html.EscapeAttribute(href) + `">&lt;` + html.EscapeCharData(name) + `&gt;</a>`
+	case "synnote":
+		s = `<span class="synnote">(` + html.EscapeCharData(node.Text) + `)</span>`
+	case "synnoteref":
+		s = `<span class="synnoteref">(` + html.EscapeCharData(node.attr("href")) + `)</span>`
+	case "groupseq", "groupchoice", "groupcomp", "synblk":
+		s = renderGroup(node)
+	case "repsep", "title":
+		return ""
+	default:
+		s = html.EscapeCharData(node.Text)
+	}
+
+	if node.Kind != "groupseq" && node.Kind != "groupchoice" && node.attr("importance") == "optional" {
+		s = "[" + s + "]"
+	}
+	return s
+}
+
+func renderGroup(node *synnode) string {
+	var parts []string
+	for _, child := range node.Children {
+		if part := renderSyntax(child); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	optional := node.attr("importance") == "optional"
+
+	var s string
+	switch node.Kind {
+	case "groupchoice":
+		s = strings.Join(parts, " | ")
+		if optional {
+			s = "[ " + s + " ]"
+		} else {
+			s = "{ " + s + " }"
+		}
+	case "groupcomp":
+		s = strings.Join(parts, "")
+	default:
+		s = strings.Join(parts, " ")
+		if optional {
+			s = "[ " + s + " ]"
+		}
+	}
+
+	if repsep := node.child("repsep"); repsep != nil {
+		if repsep.Text == "" {
+			s += " ..."
+		} else {
+			s += ` [<span class="repsep">` + html.EscapeCharData(repsep.Text) + `</span> ...]`
+		}
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyntaxDiagramReuse(t *testing.T) {
+	tests := []struct {
+		name    string
+		diagram string
+		want    string
+	}{
+		{
+			name:    "conref",
+			diagram: `<groupseq><kwd>run</kwd><groupchoice conref="lib.dita#lib/modes"/></groupseq>`,
+			want:    `<kbd class="kwd">run</kbd> { <kbd class="kwd">fast</kbd> | <kbd class="kwd">safe</kbd> }`,
+		},
+		{
+			name:    "conkeyref",
+			diagram: `<groupseq><kwd>run</kwd><groupchoice conkeyref="lib/modes"/></groupseq>`,
+			want:    `<kbd class="kwd">run</kbd> { <kbd class="kwd">fast</kbd> | <kbd class="kwd">safe</kbd> }`,
+		},
+		{
+			name:    "conref range",
+			diagram: `<groupseq><kwd conref="lib.dita#lib/first" conrefend="lib.dita#lib/last"/></groupseq>`,
+			want:    `<kbd class="kwd">first</kbd> <var class="var">second</var> <kbd class="kwd">last</kbd>`,
+		},
+		{
+			name:    "excluded conref",
+			diagram: `<groupseq><kwd>run</kwd><groupchoice product="old" conref="lib.dita#lib/modes"/></groupseq>`,
+			want:    `<pre class="syntax"><kbd class="kwd">run</kbd></pre>`,
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map>
+				<keydef keys="lib" href="lib.dita"/>
+				<topicref href="t.dita"/>
+			</map>`,
+			"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+			"lib.dita": `<reference id="lib"><title>Library</title><refbody><refsyn>
+				<syntaxdiagram>
+					<groupchoice id="modes"><kwd>fast</kwd><kwd product="old">legacy</kwd><kwd>safe</kwd></groupchoice>
+					<kwd id="first">first</kwd><var>second</var><kwd id="last">last</kwd>
+				</syntaxdiagram>
+			</refsyn></refbody></reference>`,
+			"t.dita": `<reference id="t"><title>T</title><refbody><refsyn>
+				<syntaxdiagram>` + test.diagram + `</syntaxdiagram>
+			</refsyn></refbody></reference>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		if !strings.Contains(output, test.want) {
+			t.Errorf("%s: missing %q:\n%s", test.name, test.want, output)
+		}
+	}
+}
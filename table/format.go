@@ -0,0 +1,136 @@
+package table
+
+import "strings"
+
+// Format contains the formatting attributes of a cell
+// after inheritance from row, spanspec, colspec, tgroup and table
+type Format struct {
+	ColSep string
+	RowSep string
+	Align  string
+	Char   string
+	VAlign string
+}
+
+// FormatAttributes are removed from the output, they are
+// translated into classes
+var FormatAttributes = []string{
+	"frame", "colsep", "rowsep", "align", "char", "charoff",
+	"valign", "pgwide", "rowheader", "cols", "orient", "scale", "expanse",
+}
+
+// ClearFormat removes formatting attributes
+func (el *Attributes) ClearFormat() {
+	for _, name := range FormatAttributes {
+		el.SetAttr(name, "")
+	}
+}
+
+// CellFormat resolves the formatting of cell in row, parent contains the
+// attributes of the table element.
+func (table *TableGroup) CellFormat(parent *Attributes, section *Section, row *Row, cell *Cell) Format {
+	var span *SpanSpec
+	var col *ColSpec
+	if cell.Entry != nil {
+		if name := cell.Entry.GetAttr("spanname"); name != "" {
+			span = table.SpanSpec(name)
+		}
+	}
+	if specs, _ := table.columns(); cell.Col < len(specs) {
+		col = specs[cell.Col]
+	}
+
+	entry := func(name string) string {
+		if cell.Entry == nil {
+			return ""
+		}
+		return cell.Entry.GetAttr(name)
+	}
+	spanspec := func(value func(*SpanSpec) string) string {
+		if span == nil {
+			return ""
+		}
+		return value(span)
+	}
+	colspec := func(value func(*ColSpec) string) string {
+		if col == nil {
+			return ""
+		}
+		return value(col)
+	}
+
+	return Format{
+		ColSep: first(
+			entry("colsep"),
+			spanspec(func(s *SpanSpec) string { return s.ColSep }),
+			colspec(func(c *ColSpec) string { return c.ColSep }),
+			table.GetAttr("colsep"),
+			parent.GetAttr("colsep"),
+		),
+		RowSep: first(
+			entry("rowsep"),
+			row.GetAttr("rowsep"),
+			spanspec(func(s *SpanSpec) string { return s.RowSep }),
+			colspec(func(c *ColSpec) string { return c.RowSep }),
+			table.GetAttr("rowsep"),
+			parent.GetAttr("rowsep"),
+		),
+		Align: first(
+			entry("align"),
+			spanspec(func(s *SpanSpec) string { return s.Align }),
+			colspec(func(c *ColSpec) string { return c.Align }),
+			table.GetAttr("align"),
+		),
+		Char: first(
+			entry("char"),
+			spanspec(func(s *SpanSpec) string { return s.Char }),
+			colspec(func(c *ColSpec) string { return c.Char }),
+		),
+		VAlign: first(
+			entry("valign"),
+			row.GetAttr("valign"),
+			section.GetAttr("valign"),
+		),
+	}
+}
+
+// Classes returns CSS classes for the format, e.g. "colsep-1 align-center"
+func (format Format) Classes() string {
+	var classes []string
+	add := func(prefix, value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			classes = append(classes, prefix+"-"+value)
+		}
+	}
+	add("colsep", format.ColSep)
+	add("rowsep", format.RowSep)
+	add("align", format.Align)
+	add("valign", format.VAlign)
+	return strings.Join(classes, " ")
+}
+
+// TableClasses returns CSS classes for the table attributes frame,
+// expanse and pgwide
+func TableClasses(parent *Attributes) string {
+	var classes []string
+	if frame := strings.TrimSpace(parent.GetAttr("frame")); frame != "" {
+		classes = append(classes, "frame-"+strings.ToLower(frame))
+	}
+	if expanse := strings.TrimSpace(parent.GetAttr("expanse")); expanse != "" {
+		classes = append(classes, "expanse-"+strings.ToLower(expanse))
+	}
+	if strings.TrimSpace(parent.GetAttr("pgwide")) == "1" {
+		classes = append(classes, "pgwide")
+	}
+	return strings.Join(classes, " ")
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
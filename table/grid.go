@@ -0,0 +1,376 @@
+package table
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grid contains the entries of a tgroup positioned in rows and columns
+type Grid struct {
+	// Columns contains the colspec of each grid column, nil if not specified
+	Columns []*ColSpec
+
+	Head []GridRow
+	Body []GridRow
+	Foot []GridRow
+
+	// Problems found while laying out the table
+	Problems []Problem
+}
+
+// GridRow contains the cells starting in the row
+type GridRow struct {
+	Row *Row
+	// Number is the 1-based position of the row in the source section
+	Number int
+	Cells  []Cell
+}
+
+// Problem is an error in the table definition, Row and Col are 1-based
+// positions in the source section, 0 when not applicable
+type Problem struct {
+	Section string
+	Row     int
+	Col     int
+	Message string
+}
+
+func (problem Problem) Error() string {
+	switch {
+	case problem.Section == "":
+		return problem.Message
+	case problem.Col == 0:
+		return fmt.Sprintf("%s row %d: %s", problem.Section, problem.Row, problem.Message)
+	}
+	return fmt.Sprintf("%s row %d, column %d: %s", problem.Section, problem.Row, problem.Col, problem.Message)
+}
+
+// Cell is an entry positioned in the grid, Row and Col are zero-based
+// and relative to the section. Entry is nil for empty cells filling the grid.
+type Cell struct {
+	Entry   *Entry
+	Row     int
+	Col     int
+	RowSpan int
+	ColSpan int
+}
+
+// OverlapsCols checks whether cell and other share a column
+func (cell *Cell) OverlapsCols(other Cell) bool {
+	return cell.Col < other.Col+other.ColSpan && other.Col < cell.Col+cell.ColSpan
+}
+
+// OverlapsRows checks whether cell and other share a row
+func (cell *Cell) OverlapsRows(other Cell) bool {
+	return cell.Row < other.Row+other.RowSpan && other.Row < cell.Row+cell.RowSpan
+}
+
+// HasSpans checks whether any cell spans multiple rows or columns
+func (grid *Grid) HasSpans() bool {
+	for _, rows := range [][]GridRow{grid.Head, grid.Body, grid.Foot} {
+		for _, row := range rows {
+			for _, cell := range row.Cells {
+				if cell.RowSpan > 1 || cell.ColSpan > 1 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// columns returns colspecs by grid column and the number of columns,
+// colspecs without colnum follow the previous colspec
+func (table *TableGroup) columns() (specs []*ColSpec, count int) {
+	next := 0
+	for i := range table.Column {
+		spec := &table.Column[i]
+		if num, err := strconv.Atoi(strings.TrimSpace(spec.Num)); err == nil && num > 0 {
+			next = num - 1
+		}
+		for len(specs) <= next {
+			specs = append(specs, nil)
+		}
+		specs[next] = spec
+		next++
+	}
+
+	count = len(specs)
+	if cols, err := strconv.Atoi(strings.TrimSpace(table.GetAttr("cols"))); err == nil && cols > count {
+		count = cols
+	}
+	for len(specs) < count {
+		specs = append(specs, nil)
+	}
+	return specs, count
+}
+
+// Layout computes the grid of the table group, rows for which include
+// returns false are left out. A nil include keeps all rows.
+//
+// Problems found while positioning the entries are added to Grid.Problems.
+func (table *TableGroup) Layout(include func(section *Section, row *Row) bool) *Grid {
+	specs, count := table.columns()
+	grid := &Grid{Columns: specs}
+	grid.Problems = table.validateSpecs()
+
+	declared := count
+	if cols, err := strconv.Atoi(strings.TrimSpace(table.GetAttr("cols"))); err == nil && cols > 0 {
+		declared = cols
+	}
+
+	type laidout struct {
+		name     string
+		rows     []GridRow
+		occupied map[[2]int]bool
+	}
+
+	layout := func(name string, section *Section) laidout {
+		result := laidout{name: name, occupied: map[[2]int]bool{}}
+		if section == nil {
+			return result
+		}
+
+		occupied := result.occupied
+		for i := range section.Rows {
+			row := &section.Rows[i]
+			if include != nil && !include(section, row) {
+				continue
+			}
+
+			r := len(result.rows)
+			gridrow := GridRow{Row: row, Number: i + 1}
+			problem := func(col int, format string, args ...interface{}) {
+				grid.Problems = append(grid.Problems, Problem{
+					Section: name,
+					Row:     i + 1,
+					Col:     col,
+					Message: fmt.Sprintf(format, args...),
+				})
+			}
+
+			next := 0
+			overflow := false
+			for k := range row.Entries {
+				entry := &row.Entries[k]
+				start, end := table.EntryColumns(entry)
+				if start < 0 {
+					start = next
+					for occupied[[2]int{r, start}] {
+						start++
+					}
+					end = start
+				}
+				for _, attr := range []string{"namest", "nameend", "colname"} {
+					if colname := entry.GetAttr(attr); colname != "" && table.ColumnIndex(colname) < 0 {
+						problem(start+1, "unknown %s %q", attr, colname)
+					}
+				}
+				if spanname := entry.GetAttr("spanname"); spanname != "" && table.SpanSpec(spanname) == nil {
+					problem(start+1, "unknown spanname %q", spanname)
+				}
+				if end < start {
+					// unknown nameend is reported above
+					if end >= 0 {
+						problem(start+1, "namest %q is after nameend %q", entry.GetAttr("namest"), entry.GetAttr("nameend"))
+					}
+					end = start
+				}
+
+				rowspan := 1
+				if value := strings.TrimSpace(entry.GetAttr("morerows")); value != "" {
+					morerows, err := strconv.Atoi(value)
+					if err != nil || morerows < 0 {
+						problem(start+1, "invalid morerows %q", value)
+					} else {
+						rowspan = morerows + 1
+					}
+				}
+
+				overlaps := false
+				for y := r; y < r+rowspan; y++ {
+					for x := start; x <= end; x++ {
+						overlaps = overlaps || occupied[[2]int{y, x}]
+						occupied[[2]int{y, x}] = true
+					}
+				}
+				if overlaps {
+					problem(start+1, "entry overlaps another entry")
+				}
+
+				if end+1 > declared && !overflow {
+					problem(end+1, "row has more entries than cols=%d", declared)
+					overflow = true
+				}
+				if end+1 > count {
+					count = end + 1
+				}
+
+				gridrow.Cells = append(gridrow.Cells, Cell{
+					Entry:   entry,
+					Row:     r,
+					Col:     start,
+					RowSpan: rowspan,
+					ColSpan: end - start + 1,
+				})
+				next = end + 1
+			}
+			result.rows = append(result.rows, gridrow)
+		}
+		return result
+	}
+
+	// complete fills the columns not covered by any entry with empty
+	// cells and limits row spans to the section
+	complete := func(section laidout) []GridRow {
+		for r := range section.rows {
+			row := &section.rows[r]
+			sort.SliceStable(row.Cells, func(i, k int) bool {
+				return row.Cells[i].Col < row.Cells[k].Col
+			})
+
+			problem := func(col int, message string) {
+				grid.Problems = append(grid.Problems, Problem{
+					Section: section.name,
+					Row:     row.Number,
+					Col:     col,
+					Message: message,
+				})
+			}
+
+			var cells []Cell
+			missing := false
+			k := 0
+			for x := 0; x < count; x++ {
+				if k < len(row.Cells) && row.Cells[k].Col == x {
+					cell := row.Cells[k]
+					if cell.Row+cell.RowSpan > len(section.rows) {
+						problem(x+1, "morerows extends past the last row")
+						cell.RowSpan = len(section.rows) - cell.Row
+					}
+					cells = append(cells, cell)
+					x += cell.ColSpan - 1
+					k++
+					continue
+				}
+				if !section.occupied[[2]int{r, x}] {
+					if !missing && x < declared {
+						problem(x+1, "row has fewer entries than columns")
+						missing = true
+					}
+					cells = append(cells, Cell{Row: r, Col: x, RowSpan: 1, ColSpan: 1})
+				}
+			}
+			// cells overlapping others are kept in their original order
+			cells = append(cells, row.Cells[k:]...)
+			row.Cells = cells
+		}
+		return section.rows
+	}
+
+	head := layout("thead", table.Head)
+	body := layout("tbody", table.Body)
+	foot := layout("tfoot", table.Foot)
+	grid.Head = complete(head)
+	grid.Body = complete(body)
+	grid.Foot = complete(foot)
+
+	for len(grid.Columns) < count {
+		grid.Columns = append(grid.Columns, nil)
+	}
+
+	order := map[string]int{"": 0, "thead": 1, "tbody": 2, "tfoot": 3}
+	sort.SliceStable(grid.Problems, func(i, k int) bool {
+		a, b := grid.Problems[i], grid.Problems[k]
+		if order[a.Section] != order[b.Section] {
+			return order[a.Section] < order[b.Section]
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+	return grid
+}
+
+// validateSpecs checks cols, colspec and spanspec definitions
+func (table *TableGroup) validateSpecs() []Problem {
+	var problems []Problem
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, Problem{Message: fmt.Sprintf(format, args...)})
+	}
+
+	cols := 0
+	if value := strings.TrimSpace(table.GetAttr("cols")); value == "" {
+		problem("tgroup is missing cols")
+	} else if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+		problem("invalid cols %q", value)
+	} else {
+		cols = n
+	}
+
+	names := map[string]bool{}
+	next := 0
+	for i, spec := range table.Column {
+		if value := strings.TrimSpace(spec.Num); value != "" {
+			if num, err := strconv.Atoi(value); err != nil || num <= 0 {
+				problem("colspec %d: invalid colnum %q", i+1, value)
+			} else {
+				next = num - 1
+			}
+		}
+		next++
+		if cols > 0 && next > cols {
+			problem("colspec %d: column %d is outside cols=%d", i+1, next, cols)
+		}
+
+		if name := strings.ToLower(spec.Name); name != "" {
+			if names[name] {
+				problem("colspec %d: duplicate colname %q", i+1, spec.Name)
+			}
+			names[name] = true
+		}
+		if _, err := ParseColWidth(spec.Width); err != nil {
+			problem("colspec %d: %v", i+1, err)
+		}
+	}
+
+	for _, span := range table.Span {
+		for _, colname := range []string{span.Start, span.End} {
+			if table.ColumnIndex(colname) < 0 {
+				problem("spanspec %q: unknown column %q", span.Name, colname)
+			}
+		}
+	}
+
+	return problems
+}
+
+// EntryColumns returns the first and last grid column of entry specified
+// by namest/nameend, spanname or colname. Start is -1 when entry does not
+// specify its position.
+func (table *TableGroup) EntryColumns(entry *Entry) (start, end int) {
+	if namest := entry.GetAttr("namest"); namest != "" {
+		start = table.ColumnIndex(namest)
+		end = start
+		if nameend := entry.GetAttr("nameend"); nameend != "" {
+			end = table.ColumnIndex(nameend)
+		}
+		return start, end
+	}
+
+	if spanname := entry.GetAttr("spanname"); spanname != "" {
+		if span := table.SpanSpec(spanname); span != nil {
+			return table.ColumnIndex(span.Start), table.ColumnIndex(span.End)
+		}
+	}
+
+	if colname := entry.GetAttr("colname"); colname != "" {
+		start = table.ColumnIndex(colname)
+		return start, start
+	}
+
+	return -1, -1
+}
@@ -0,0 +1,219 @@
+package table
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseTableGroup(t *testing.T, data string) *TableGroup {
+	t.Helper()
+	table := &TableGroup{}
+	if err := xml.Unmarshal([]byte(data), table); err != nil {
+		t.Fatalf("parsing %s: %v", data, err)
+	}
+	return table
+}
+
+// layoutRows describes each row as its cells, empty cells as "_" and
+// spanning cells with their colspan and rowspan, e.g. "a(2,1) b"
+func layoutRows(rows []GridRow) []string {
+	var result []string
+	for _, row := range rows {
+		var cells []string
+		for _, cell := range row.Cells {
+			text := "_"
+			if cell.Entry != nil {
+				text = strings.TrimSpace(string(cell.Entry.Content))
+			}
+			if cell.ColSpan > 1 || cell.RowSpan > 1 {
+				text += fmt.Sprintf("(%d,%d)", cell.ColSpan, cell.RowSpan)
+			}
+			cells = append(cells, text)
+		}
+		result = append(result, strings.Join(cells, " "))
+	}
+	return result
+}
+
+func problemMessages(problems []Problem) []string {
+	var result []string
+	for _, problem := range problems {
+		result = append(result, problem.Error())
+	}
+	return result
+}
+
+const threeColumns = `<colspec colname="c1"/><colspec colname="c2"/><colspec colname="c3"/>`
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		tgroup   string
+		rows     []string
+		problems []string
+	}{
+		{
+			name:   "plain",
+			tgroup: `<tgroup cols="2"><tbody><row><entry>a</entry><entry>b</entry></row><row><entry>c</entry><entry>d</entry></row></tbody></tgroup>`,
+			rows:   []string{"a b", "c d"},
+		},
+		{
+			name:   "namest and nameend",
+			tgroup: `<tgroup cols="3">` + threeColumns + `<tbody><row><entry namest="c1" nameend="c2">a</entry><entry>b</entry></row></tbody></tgroup>`,
+			rows:   []string{"a(2,1) b"},
+		},
+		{
+			name:   "spanspec",
+			tgroup: `<tgroup cols="3">` + threeColumns + `<spanspec spanname="s" namest="c2" nameend="c3"/><tbody><row><entry>a</entry><entry spanname="s">b</entry></row></tbody></tgroup>`,
+			rows:   []string{"a b(2,1)"},
+		},
+		{
+			name:   "colname",
+			tgroup: `<tgroup cols="3">` + threeColumns + `<tbody><row><entry colname="c2">a</entry><entry>b</entry></row></tbody></tgroup>`,
+			rows:   []string{"_ a b"},
+			problems: []string{
+				"tbody row 1, column 1: row has fewer entries than columns",
+			},
+		},
+		{
+			name:   "morerows",
+			tgroup: `<tgroup cols="2"><tbody><row><entry morerows="1">a</entry><entry>b</entry></row><row><entry>c</entry></row></tbody></tgroup>`,
+			rows:   []string{"a(1,2) b", "c"},
+		},
+		{
+			name:   "overlap",
+			tgroup: `<tgroup cols="2"><colspec colname="c1"/><colspec colname="c2"/><tbody><row><entry morerows="1">a</entry><entry>b</entry></row><row><entry colname="c1">c</entry><entry>d</entry></row></tbody></tgroup>`,
+			rows:   []string{"a(1,2) b", "c d"},
+			problems: []string{
+				"tbody row 2, column 1: entry overlaps another entry",
+			},
+		},
+		{
+			name:   "morerows past the last row",
+			tgroup: `<tgroup cols="2"><tbody><row><entry morerows="2">a</entry><entry>b</entry></row></tbody></tgroup>`,
+			rows:   []string{"a b"},
+			problems: []string{
+				"tbody row 1, column 1: morerows extends past the last row",
+			},
+		},
+		{
+			name:   "invalid morerows",
+			tgroup: `<tgroup cols="1"><tbody><row><entry morerows="x">a</entry></row></tbody></tgroup>`,
+			rows:   []string{"a"},
+			problems: []string{
+				`tbody row 1, column 1: invalid morerows "x"`,
+			},
+		},
+		{
+			name:   "fewer entries",
+			tgroup: `<tgroup cols="3"><tbody><row><entry>a</entry></row></tbody></tgroup>`,
+			rows:   []string{"a _ _"},
+			problems: []string{
+				"tbody row 1, column 2: row has fewer entries than columns",
+			},
+		},
+		{
+			name:   "more entries",
+			tgroup: `<tgroup cols="1"><tbody><row><entry>a</entry><entry>b</entry></row></tbody></tgroup>`,
+			rows:   []string{"a b"},
+			problems: []string{
+				"tbody row 1, column 2: row has more entries than cols=1",
+			},
+		},
+		{
+			name:   "unknown names",
+			tgroup: `<tgroup cols="2"><colspec colname="c1"/><colspec colname="c2"/><tbody><row><entry colname="x">a</entry><entry spanname="s">b</entry></row></tbody></tgroup>`,
+			rows:   []string{"a b"},
+			problems: []string{
+				`tbody row 1, column 1: unknown colname "x"`,
+				`tbody row 1, column 2: unknown spanname "s"`,
+			},
+		},
+		{
+			name:   "namest after nameend",
+			tgroup: `<tgroup cols="3">` + threeColumns + `<tbody><row><entry namest="c2" nameend="c1">a</entry><entry>b</entry><entry>c</entry></row></tbody></tgroup>`,
+			rows:   []string{"_ a b c"},
+			problems: []string{
+				"tbody row 1, column 1: row has fewer entries than columns",
+				`tbody row 1, column 2: namest "c2" is after nameend "c1"`,
+				"tbody row 1, column 4: row has more entries than cols=3",
+			},
+		},
+		{
+			name:   "excluded row",
+			tgroup: `<tgroup cols="2"><tbody><row><entry morerows="1">a</entry><entry>b</entry></row><row product="old"><entry>x</entry></row><row><entry>c</entry></row></tbody></tgroup>`,
+			rows:   []string{"a(1,2) b", "c"},
+		},
+	}
+
+	include := func(section *Section, row *Row) bool { return row.GetAttr("product") != "old" }
+	for _, test := range tests {
+		grid := parseTableGroup(t, test.tgroup).Layout(include)
+
+		if rows := layoutRows(grid.Body); !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%s: got rows %q, expected %q", test.name, rows, test.rows)
+		}
+		if problems := problemMessages(grid.Problems); !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: got problems %q, expected %q", test.name, problems, test.problems)
+		}
+	}
+}
+
+func TestValidateSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		tgroup   string
+		problems []string
+	}{
+		{
+			name:   "valid",
+			tgroup: `<tgroup cols="3"><colspec colname="c1" colwidth="2*"/><colspec colnum="3" colname="c3" colwidth="20pt"/><spanspec spanname="s" namest="c1" nameend="c3"/></tgroup>`,
+		},
+		{
+			name:     "missing cols",
+			tgroup:   `<tgroup></tgroup>`,
+			problems: []string{"tgroup is missing cols"},
+		},
+		{
+			name:     "invalid cols",
+			tgroup:   `<tgroup cols="0"></tgroup>`,
+			problems: []string{`invalid cols "0"`},
+		},
+		{
+			name:     "invalid colnum",
+			tgroup:   `<tgroup cols="2"><colspec colnum="x"/></tgroup>`,
+			problems: []string{`colspec 1: invalid colnum "x"`},
+		},
+		{
+			name:   "colspec outside cols",
+			tgroup: `<tgroup cols="2"><colspec colname="c1"/><colspec colname="c2"/><colspec colname="c3"/><colspec colnum="5"/></tgroup>`,
+			problems: []string{
+				"colspec 3: column 3 is outside cols=2",
+				"colspec 4: column 5 is outside cols=2",
+			},
+		},
+		{
+			name:     "duplicate colname",
+			tgroup:   `<tgroup cols="2"><colspec colname="c1"/><colspec colname="C1"/></tgroup>`,
+			problems: []string{`colspec 2: duplicate colname "C1"`},
+		},
+		{
+			name:   "unknown spanspec columns",
+			tgroup: `<tgroup cols="2"><colspec colname="c1"/><spanspec spanname="s" namest="c1" nameend="c9"/><spanspec spanname="t" namest="x" nameend="c1"/></tgroup>`,
+			problems: []string{
+				`spanspec "s": unknown column "c9"`,
+				`spanspec "t": unknown column "x"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		problems := problemMessages(parseTableGroup(t, test.tgroup).validateSpecs())
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: got problems %q, expected %q", test.name, problems, test.problems)
+		}
+	}
+}
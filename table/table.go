@@ -1,12 +1,17 @@
 package table
 
-import "strings"
+import (
+	"encoding/xml"
+	"strings"
+)
 
 type XML struct {
 	Attributes
 	XMLInner
 }
 type XMLInner struct {
+	Title  *Entry       `xml:"title"`
+	Desc   *Entry       `xml:"desc"`
 	Groups []TableGroup `xml:"tgroup"`
 }
 
@@ -15,24 +20,63 @@ type TableGroup struct {
 	TableGroupInner
 }
 type TableGroupInner struct {
-	Column []ColSpec `xml:"colspec"`
-	Head   []Entry   `xml:"thead>row>entry"`
-	Rows   []Row     `xml:"tbody>row"`
+	Column []ColSpec  `xml:"colspec"`
+	Span   []SpanSpec `xml:"spanspec"`
+	Head   *Section   `xml:"thead"`
+	Foot   *Section   `xml:"tfoot"`
+	Body   *Section   `xml:"tbody"`
 }
 
+// ColumnIndex returns the zero-based grid column of colname, -1 if not found
 func (table *TableGroup) ColumnIndex(name string) int {
-	for i, col := range table.Column {
-		if strings.EqualFold(col.Name, name) {
+	specs, _ := table.columns()
+	for i, col := range specs {
+		if col != nil && strings.EqualFold(col.Name, name) {
 			return i
 		}
 	}
 	return -1
 }
 
+// SpanSpec returns the spanspec with spanname, nil if not found
+func (table *TableGroup) SpanSpec(name string) *SpanSpec {
+	for i := range table.Span {
+		if strings.EqualFold(table.Span[i].Name, name) {
+			return &table.Span[i]
+		}
+	}
+	return nil
+}
+
 type ColSpec struct {
 	Name  string `xml:"colname,attr"`
-	Num   int    `xml:"colnum,attr"`
+	Num   string `xml:"colnum,attr"`
 	Width string `xml:"colwidth,attr"`
+
+	ColSep string `xml:"colsep,attr"`
+	RowSep string `xml:"rowsep,attr"`
+	Align  string `xml:"align,attr"`
+	Char   string `xml:"char,attr"`
+}
+
+type SpanSpec struct {
+	Name  string `xml:"spanname,attr"`
+	Start string `xml:"namest,attr"`
+	End   string `xml:"nameend,attr"`
+
+	ColSep string `xml:"colsep,attr"`
+	RowSep string `xml:"rowsep,attr"`
+	Align  string `xml:"align,attr"`
+	Char   string `xml:"char,attr"`
+}
+
+// Section is thead, tbody or tfoot
+type Section struct {
+	Attributes
+	SectionInner
+}
+type SectionInner struct {
+	Rows []Row `xml:"row"`
 }
 
 type Entry struct {
@@ -40,14 +84,19 @@ type Entry struct {
 	EntryInner
 }
 type EntryInner struct {
+	XMLName xml.Name
 	Content []byte `xml:",innerxml"`
 }
 
 func (e *Entry) Bounds() (start, end string) { return e.GetAttr("namest"), e.GetAttr("nameend") }
 
+// ClearBounds removes the attributes that position the entry in the grid
 func (e *Entry) ClearBounds() {
 	e.SetAttr("namest", "")
 	e.SetAttr("nameend", "")
+	e.SetAttr("spanname", "")
+	e.SetAttr("colname", "")
+	e.SetAttr("morerows", "")
 }
 
 type Row struct {
@@ -58,13 +107,15 @@ type RowInner struct {
 	Entries []Entry `xml:"entry"`
 }
 
+// SimpleXML is simpletable or its specialization, e.g. choicetable
 type SimpleXML struct {
 	Attributes
 	SimpleXMLInner
 }
 type SimpleXMLInner struct {
-	Head []Entry     `xml:"sthead>stentry"`
-	Rows []SimpleRow `xml:"strow"`
+	Title *Entry `xml:"title"`
+	// Parts contains sthead and strow elements or their specializations
+	Parts []SimpleRow `xml:",any"`
 }
 
 type SimpleRow struct {
@@ -72,7 +123,8 @@ type SimpleRow struct {
 	SimpleRowInner
 }
 type SimpleRowInner struct {
-	Entries []Entry `xml:"stentry"`
+	XMLName xml.Name
+	Entries []Entry `xml:",any"`
 }
 
 type SettingsXML struct {
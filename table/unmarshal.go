@@ -12,6 +12,11 @@ func (el *TableGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 	return d.DecodeElement(&el.TableGroupInner, &start)
 }
 
+func (el *Section) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	el.Attr = append(el.Attr, start.Attr...)
+	return d.DecodeElement(&el.SectionInner, &start)
+}
+
 func (el *Entry) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	el.Attr = append(el.Attr, start.Attr...)
 	return d.DecodeElement(&el.EntryInner, &start)
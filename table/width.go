@@ -0,0 +1,148 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColWidth is a parsed CALS colwidth, e.g. "2*+3pt"
+type ColWidth struct {
+	// Proportion is the proportional part, "2*" --> 2
+	Proportion float64
+	// Fixed contains CSS lengths of the fixed parts, "3pt"
+	Fixed []string
+}
+
+// cssUnits maps CALS units to CSS units, unitless values are points
+var cssUnits = map[string]string{
+	"":   "pt",
+	"pt": "pt",
+	"pc": "pc",
+	"pi": "pc",
+	"in": "in",
+	"cm": "cm",
+	"mm": "mm",
+	"px": "px",
+	"em": "em",
+}
+
+// ParseColWidth parses colwidth, an empty value is the default "1*"
+func ParseColWidth(value string) (ColWidth, error) {
+	var width ColWidth
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		width.Proportion = 1
+		return width, nil
+	}
+
+	for _, term := range strings.Split(value, "+") {
+		term = strings.TrimSpace(term)
+		if strings.HasSuffix(term, "*") {
+			number := strings.TrimSpace(strings.TrimSuffix(term, "*"))
+			if number == "" {
+				width.Proportion++
+				continue
+			}
+			v, err := strconv.ParseFloat(number, 64)
+			if err != nil || v < 0 {
+				return width, fmt.Errorf("invalid colwidth %q", value)
+			}
+			width.Proportion += v
+			continue
+		}
+
+		i := strings.IndexFunc(term, func(r rune) bool {
+			return !('0' <= r && r <= '9' || r == '.')
+		})
+		if i < 0 {
+			i = len(term)
+		}
+		v, err := strconv.ParseFloat(term[:i], 64)
+		unit, known := cssUnits[strings.ToLower(strings.TrimSpace(term[i:]))]
+		if err != nil || !known || v < 0 {
+			return width, fmt.Errorf("invalid colwidth %q", value)
+		}
+		width.Fixed = append(width.Fixed, formatNumber(v)+unit)
+	}
+
+	return width, nil
+}
+
+// ColumnWidths returns CSS widths for the columns, proportional widths
+// share the space left from fixed widths. Nil is returned when no
+// column specifies a width.
+func ColumnWidths(specs []*ColSpec) []string {
+	specified := false
+	widths := make([]ColWidth, len(specs))
+	for i, spec := range specs {
+		value := ""
+		if spec != nil {
+			value = spec.Width
+		}
+		specified = specified || strings.TrimSpace(value) != ""
+
+		width, err := ParseColWidth(value)
+		if err != nil {
+			// invalid widths are reported by validation
+			width, _ = ParseColWidth("")
+		}
+		widths[i] = width
+	}
+	if !specified {
+		return nil
+	}
+
+	total := 0.0
+	var fixed []string
+	for _, width := range widths {
+		total += width.Proportion
+		fixed = append(fixed, width.Fixed...)
+	}
+
+	// space shared by proportional widths
+	remaining := "100%"
+	if len(fixed) > 0 {
+		remaining = "(100% - " + strings.Join(fixed, " - ") + ")"
+	}
+
+	css := make([]string, len(widths))
+	for i, width := range widths {
+		var terms []string
+		if width.Proportion > 0 && total > 0 {
+			share := width.Proportion / total
+			if len(fixed) == 0 {
+				terms = append(terms, FormatPercent(share))
+			} else {
+				terms = append(terms, remaining+" * "+formatNumber(share))
+			}
+		}
+		terms = append(terms, width.Fixed...)
+
+		switch len(terms) {
+		case 0:
+			css[i] = "0"
+		case 1:
+			css[i] = terms[0]
+		default:
+			css[i] = strings.Join(terms, " + ")
+		}
+		if len(fixed) > 0 && width.Proportion > 0 {
+			css[i] = "calc(" + css[i] + ")"
+		}
+	}
+	return css
+}
+
+// FormatPercent formats fraction as a CSS percentage, 0.25 --> "25%"
+func FormatPercent(fraction float64) string {
+	return formatNumber(fraction*100) + "%"
+}
+
+// formatNumber formats v with at most 4 decimals
+func formatNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/raintreeinc/ditaconvert/html"
 	"github.com/raintreeinc/ditaconvert/table"
 )
 
@@ -61,66 +62,205 @@ func HandleTable(context *Context, dec *xml.Decoder, start xml.StartElement) err
 		}
 	}
 
-	emitStart("div", t.Attr...)
+	emitStart("div", formatFree(t.Attributes)...)
 	defer emitEnd("div")
 
-	for _, group := range t.Groups {
-		emitStart("table", group.Attr...)
+	rowheader := t.GetAttr("rowheader") == "firstcol"
+	for i, group := range t.Groups {
+		groupConditions, included := context.ElementConditions(context.Conditions, "tgroup", group.Attr)
+		if !included {
+			continue
+		}
+
+		tableAttrs := table.Attributes{Attr: formatFree(group.Attributes)}
+		addClass(&tableAttrs, table.TableClasses(&t.Attributes))
+		emitStart("table", tableAttrs.Attr...)
+
+		if i == 0 && (t.Title != nil || t.Desc != nil) {
+			emitStart("caption")
+			if t.Title != nil {
+				context.writeCaptionLabel("table", t.GetAttr("id"))
+				recurse(t.Title.Content)
+			}
+			if t.Desc != nil {
+				emitStart("div", attr("class", "tabledesc"))
+				recurse(t.Desc.Content)
+				emitEnd("div")
+			}
+			emitEnd("caption")
+		}
+
+		// rows are filtered by the conditions cascaded through tgroup and
+		// the section, the content of the row is rendered with them
+		type sectionState struct {
+			conditions Conditions
+			included   bool
+		}
+		sections := map[*table.Section]sectionState{}
+		rowConditions := map[*table.Row]Conditions{}
+		grid := group.Layout(func(section *table.Section, row *table.Row) bool {
+			state, ok := sections[section]
+			if !ok {
+				state.conditions, state.included = context.ElementConditions(groupConditions, "section", section.Attr)
+				sections[section] = state
+			}
+			if !state.included {
+				return false
+			}
 
-		widths := []string{}
-		for _, spec := range group.Column {
-			w := spec.Width
-			if strings.HasSuffix(w, "*") {
-				w = strings.TrimRight(w, "*") + "%"
+			conditions, included := context.ElementConditions(state.conditions, "row", row.Attr)
+			rowConditions[row] = conditions
+			return included
+		})
+		for _, problem := range grid.Problems {
+			context.errorf("table %q tgroup %d: %v", t.GetAttr("id"), i+1, problem)
+		}
+
+		if widths := table.ColumnWidths(grid.Columns); widths != nil {
+			emitStart("colgroup")
+			for _, w := range widths {
+				emitStart("col", attr("style", "width:"+w+";"))
+				emitEnd("col")
 			}
-			widths = append(widths, w)
+			emitEnd("colgroup")
 		}
 
-		{
-			emitStart("thead")
-			for i, head := range group.Head {
-				if i < len(widths) {
-					head.SetAttr("style", "width:"+widths[i]+";")
+		// tables with spanning cells or multiple header rows associate
+		// data cells with their header cells using id and headers
+		headerIDs := map[*table.Entry]string{}
+		if grid.HasSpans() || len(grid.Head) > 1 {
+			assign := func(entry *table.Entry) {
+				id := entry.GetAttr("id")
+				if id == "" {
+					id = context.NewAnchor("tableheader")
+					entry.SetAttr("id", id)
+				}
+				headerIDs[entry] = id
+			}
+			for _, row := range grid.Head {
+				for _, cell := range row.Cells {
+					if cell.Entry != nil {
+						assign(cell.Entry)
+					}
+				}
+			}
+			if rowheader {
+				for _, rows := range [][]table.GridRow{grid.Body, grid.Foot} {
+					for _, row := range rows {
+						for _, cell := range row.Cells {
+							if cell.Entry != nil && cell.Col == 0 {
+								assign(cell.Entry)
+							}
+						}
+					}
 				}
-				emitStart("th", head.Attr...)
-				recurse(head.Content)
-				emitEnd("th")
 			}
-			emitEnd("thead")
 		}
 
-		{
-			emitStart("tbody")
-			for _, row := range group.Rows {
-				if !isWebAudience(row.GetAttr("audience"), row.GetAttr("print"), row.GetAttr("deliveryTarget")) {
-					continue
+		// headers returns ids of header cells for cell in rows,
+		// cells in thead refer only to header cells above them
+		headers := func(rows []table.GridRow, cell table.Cell, inhead bool) string {
+			var ids []string
+			for _, row := range grid.Head {
+				for _, header := range row.Cells {
+					if inhead && header.Row >= cell.Row {
+						continue
+					}
+					if id, ok := headerIDs[header.Entry]; ok && header.OverlapsCols(cell) {
+						ids = append(ids, id)
+					}
 				}
+			}
+			if inhead {
+				return strings.Join(ids, " ")
+			}
+			for _, row := range rows {
+				for _, header := range row.Cells {
+					if header.Col != 0 || header.Entry == cell.Entry {
+						continue
+					}
+					if id, ok := headerIDs[header.Entry]; ok && header.OverlapsRows(cell) {
+						ids = append(ids, id)
+					}
+				}
+			}
+			return strings.Join(ids, " ")
+		}
+
+		emitSection := func(tag, celltag string, section *table.Section, rows []table.GridRow) {
+			if len(rows) == 0 {
+				return
+			}
+
+			emitStart(tag, formatFree(section.Attributes)...)
+			for _, row := range rows {
+				emitStart("tr", formatFree(row.Row.Attributes)...)
+				for _, cell := range row.Cells {
+					content := []byte(nil)
+					cellConditions := rowConditions[row.Row]
+					if cell.Entry != nil {
+						// excluded entries are left empty to keep the grid
+						var included bool
+						cellConditions, included = context.ElementConditions(cellConditions, "entry", cell.Entry.Attr)
+						if included {
+							content = cell.Entry.Content
+						}
+					}
+
+					format := group.CellFormat(&t.Attributes, section, row.Row, &cell)
 
-				emitStart("tr", row.Attr...)
-				for _, entry := range row.Entries {
-					if startid, endid := entry.Bounds(); startid != "" || endid != "" {
-						entry.ClearBounds()
-						si, ei := group.ColumnIndex(startid), group.ColumnIndex(endid)
-						if si >= 0 && ei >= 0 && si < ei {
-							entry.SetAttr("colspan", strconv.Itoa(ei-si+1))
+					tag := celltag
+					entry := cell.Entry
+					if entry == nil {
+						entry = &table.Entry{}
+					}
+					switch {
+					case tag == "th":
+						entry.SetAttr("scope", "col")
+						if cell.ColSpan > 1 {
+							entry.SetAttr("scope", "colgroup")
+						}
+					case rowheader && cell.Col == 0:
+						tag = "th"
+						addClass(&entry.Attributes, "rowheader")
+						entry.SetAttr("scope", "row")
+						if cell.RowSpan > 1 {
+							entry.SetAttr("scope", "rowgroup")
 						}
 					}
+					if len(headerIDs) > 0 && cell.Entry != nil {
+						entry.SetAttr("headers", headers(rows, cell, celltag == "th"))
+					}
 
-					if morerows := entry.GetAttr("morerows"); morerows != "" {
-						entry.SetAttr("morerows", "")
-						rows, _ := strconv.Atoi(morerows)
-						entry.SetAttr("rowspan", strconv.Itoa(rows+1))
+					entry.ClearBounds()
+					entry.ClearFormat()
+					addClass(&entry.Attributes, format.Classes())
+					if strings.EqualFold(format.Align, "char") && format.Char != "" {
+						entry.SetAttr("data-char", format.Char)
+					}
+					if cell.ColSpan > 1 {
+						entry.SetAttr("colspan", strconv.Itoa(cell.ColSpan))
+					}
+					if cell.RowSpan > 1 {
+						entry.SetAttr("rowspan", strconv.Itoa(cell.RowSpan))
 					}
 
-					emitStart("td", entry.Attr...)
-					recurse(entry.Content)
-					emitEnd("td")
+					emitStart(tag, entry.Attr...)
+					inherited := context.Conditions
+					context.Conditions = cellConditions
+					recurse(content)
+					context.Conditions = inherited
+					emitEnd(tag)
 				}
 				emitEnd("tr")
 			}
-			emitEnd("tbody")
+			emitEnd(tag)
 		}
 
+		emitSection("thead", "th", group.Head, grid.Head)
+		emitSection("tbody", "td", group.Body, grid.Body)
+		emitSection("tfoot", "td", group.Foot, grid.Foot)
+
 		emitEnd("table")
 	}
 
@@ -130,6 +270,43 @@ func HandleTable(context *Context, dec *xml.Decoder, start xml.StartElement) err
 	return nil
 }
 
+// formatFree returns a copy of attributes without CALS formatting attributes
+func formatFree(attrs table.Attributes) []xml.Attr {
+	clean := table.Attributes{Attr: append([]xml.Attr{}, attrs.Attr...)}
+	clean.ClearFormat()
+	return clean.Attr
+}
+
+// outputAttrs removes DITA class values and the conditional processing
+// attributes, which have been applied already
+func outputAttrs(attrs []xml.Attr) []xml.Attr {
+	var result []xml.Attr
+	for _, a := range attrs {
+		name := conditionName(a.Name)
+		switch {
+		case name == "class" && IsDITAClass(a.Value):
+		case name == "cascade":
+		case name != "xml:lang" && (mergedAttributes[name] || overriddenAttributes[name]):
+		default:
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// addClass appends class to the class attribute, DITA class values are replaced
+func addClass(attrs *table.Attributes, class string) {
+	if class == "" {
+		return
+	}
+	existing := attrs.GetAttr("class")
+	if existing == "" || IsDITAClass(existing) {
+		attrs.SetAttr("class", class)
+		return
+	}
+	attrs.SetAttr("class", existing+" "+class)
+}
+
 /*
 <simpletable>
   <sthead>
@@ -173,45 +350,205 @@ func HandleSimpleTable(context *Context, dec *xml.Decoder, start xml.StartElemen
 		}
 	}
 
-	widths := strings.Split(t.GetAttr("relcolwidth"), " ")
-	for i, w := range widths {
-		widths[i] = strings.TrimRight(w, "*") + "%"
+	name := start.Name.Local
+	kind := simpleTableKinds[name]
+
+	// split into head and rows, entries are placed into columns
+	var head *table.SimpleRow
+	var rows []*table.SimpleRow
+	// conditions cascaded to the content of rows and entries
+	partConditions := map[*table.SimpleRow]Conditions{}
+	entryConditions := map[*table.Entry]Conditions{}
+	for i := range t.Parts {
+		part := &t.Parts[i]
+		partstart := xml.StartElement{Name: part.XMLName, Attr: part.Attr}
+		conditions, included := context.ElementConditions(context.Conditions, part.XMLName.Local, part.Attr)
+		if !included {
+			continue
+		}
+		partConditions[part] = conditions
+
+		switch {
+		case IsSpecializationOf(&partstart, "sthead"):
+			head = part
+		case IsSpecializationOf(&partstart, "strow"):
+			rows = append(rows, part)
+		}
+	}
+
+	columns := 0
+	used := map[int]bool{}
+	place := func(row *table.SimpleRow, header bool) map[int]*table.Entry {
+		cells := map[int]*table.Entry{}
+		next := 0
+		for i := range row.Entries {
+			entry := &row.Entries[i]
+			conditions, included := context.ElementConditions(partConditions[row], entry.XMLName.Local, entry.Attr)
+			if !included {
+				continue
+			}
+			entryConditions[entry] = conditions
+
+			col := kind.Column(entry.XMLName.Local, header)
+			if col < 0 || cells[col] != nil {
+				col = next
+			}
+			for cells[col] != nil {
+				col++
+			}
+			cells[col] = entry
+			next = col + 1
+			if col+1 > columns {
+				columns = col + 1
+			}
+			if !header {
+				used[col] = true
+			}
+		}
+		return cells
+	}
+
+	var headcells map[int]*table.Entry
+	if head != nil {
+		headcells = place(head, true)
+		// all entries are filtered out
+		if len(headcells) == 0 {
+			head = nil
+		}
+	}
+	rowcells := make([]map[int]*table.Entry, len(rows))
+	for i, row := range rows {
+		rowcells[i] = place(row, false)
+	}
+
+	// specializations with fixed columns omit columns without content,
+	// other tables output all columns
+	var output []int
+	for col := 0; col < columns; col++ {
+		if kind.Columns != nil && !used[col] {
+			continue
+		}
+		output = append(output, col)
+	}
+
+	// relcolwidth="1* 2* 3*" --> 16.6667% 33.3333% 50%
+	var widths []string
+	if relcolwidth := strings.Fields(t.GetAttr("relcolwidth")); len(relcolwidth) > 0 {
+		proportions := make([]float64, len(output))
+		total := 0.0
+		for i, col := range output {
+			proportions[i] = 1
+			if col < len(relcolwidth) {
+				width, err := table.ParseColWidth(relcolwidth[col])
+				if err != nil || len(width.Fixed) > 0 {
+					context.errorf("%s: invalid relcolwidth %q", name, t.GetAttr("relcolwidth"))
+				} else {
+					proportions[i] = width.Proportion
+				}
+			}
+			total += proportions[i]
+		}
+		for _, proportion := range proportions {
+			if total > 0 {
+				widths = append(widths, table.FormatPercent(proportion/total))
+			}
+		}
 	}
-	t.SetAttr("relcolwidth", "")
 
-	emitStart("table", t.Attr...)
+	// keycol is 1-based
+	keycol := kind.KeyCol
+	if value := t.GetAttr("keycol"); value != "" {
+		keycol, err = strconv.Atoi(value)
+		if err != nil {
+			context.errorf("%s: invalid keycol %q", name, value)
+		}
+	}
+
+	attrs := table.Attributes{Attr: formatFree(t.Attributes)}
+	attrs.SetAttr("relcolwidth", "")
+	attrs.SetAttr("keycol", "")
+	attrs.SetAttr("expanse", "")
+	addClass(&attrs, name)
+	addClass(&attrs, table.TableClasses(&t.Attributes))
+
+	emitStart("table", attrs.Attr...)
 	defer emitEnd("table")
 
-	{
-		emitStart("thead")
-		for i, head := range t.Head {
-			if i < len(widths) {
-				head.SetAttr("style", "width:"+widths[i]+";")
+	if t.Title != nil {
+		emitStart("caption")
+		recurse(t.Title.Content)
+		emitEnd("caption")
+	}
+
+	if len(widths) > 0 {
+		emitStart("colgroup")
+		for _, w := range widths {
+			emitStart("col", attr("style", "width:"+w+";"))
+			emitEnd("col")
+		}
+		emitEnd("colgroup")
+	}
+
+	emitCell := func(tag string, col int, entry *table.Entry, scope, class string) {
+		if entry == nil {
+			entry = &table.Entry{}
+			if col < len(kind.Columns) {
+				entry.XMLName.Local = kind.Columns[col][1]
+				if scope == "col" {
+					entry.XMLName.Local = kind.Columns[col][0]
+				}
 			}
-			emitStart("th", head.Attr...)
-			recurse(head.Content)
-			emitEnd("th")
 		}
-		emitEnd("thead")
+		if kind.Columns != nil {
+			addClass(&entry.Attributes, entry.XMLName.Local)
+		}
+		addClass(&entry.Attributes, class)
+		entry.SetAttr("scope", scope)
+		emitStart(tag, outputAttrs(entry.Attr)...)
+		if conditions, ok := entryConditions[entry]; ok {
+			inherited := context.Conditions
+			context.Conditions = conditions
+			recurse(entry.Content)
+			context.Conditions = inherited
+		} else {
+			recurse(entry.Content)
+		}
+		emitEnd(tag)
 	}
 
-	{
-		emitStart("tbody")
-		for _, row := range t.Rows {
-			if !isWebAudience(row.GetAttr("audience"), row.GetAttr("print"), row.GetAttr("deliveryTarget")) {
-				continue
+	if head != nil || kind.Headers != nil {
+		emitStart("thead")
+		if head != nil {
+			emitStart("tr", outputAttrs(head.Attr)...)
+		} else {
+			emitStart("tr")
+		}
+		for _, col := range output {
+			entry := headcells[col]
+			if entry == nil && head == nil && col < len(kind.Headers) {
+				entry = &table.Entry{}
+				entry.XMLName.Local = kind.Columns[col][0]
+				entry.Content = []byte(html.EscapeCharData(kind.Headers[col]))
 			}
+			emitCell("th", col, entry, "col", "")
+		}
+		emitEnd("tr")
+		emitEnd("thead")
+	}
 
-			emitStart("tr", row.Attr...)
-			for _, entry := range row.Entries {
-				emitStart("td", entry.Attr...)
-				recurse(entry.Content)
-				emitEnd("td")
+	emitStart("tbody")
+	for i, row := range rows {
+		emitStart("tr", outputAttrs(row.Attr)...)
+		for _, col := range output {
+			if col+1 == keycol {
+				emitCell("th", col, rowcells[i][col], "row", "keycol")
+			} else {
+				emitCell("td", col, rowcells[i][col], "", "")
 			}
-			emitEnd("tr")
 		}
-		emitEnd("tbody")
+		emitEnd("tr")
 	}
+	emitEnd("tbody")
 
 	if errors != nil {
 		return fmt.Errorf("%v", errors)
@@ -219,6 +556,38 @@ func HandleSimpleTable(context *Context, dec *xml.Decoder, start xml.StartElemen
 	return nil
 }
 
+// simpleTableKind describes a specialization of simpletable
+type simpleTableKind struct {
+	// Columns contains the head and row entry names of each column
+	Columns [][2]string
+	// Headers are output when the table has no head
+	Headers []string
+	// KeyCol is the default keycol
+	KeyCol int
+}
+
+// Column returns the column of entry name, -1 if the column is not fixed
+func (kind simpleTableKind) Column(name string, header bool) int {
+	for i, names := range kind.Columns {
+		if header && names[0] == name || !header && names[1] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+var simpleTableKinds = map[string]simpleTableKind{
+	"choicetable": {
+		Columns: [][2]string{{"choptionhd", "choption"}, {"chdeschd", "chdesc"}},
+		Headers: []string{"Option", "Description"},
+		KeyCol:  1,
+	},
+	"properties": {
+		Columns: [][2]string{{"proptypehd", "proptype"}, {"propvaluehd", "propvalue"}, {"propdeschd", "propdesc"}},
+		Headers: []string{"Type", "Value", "Description"},
+	},
+}
+
 /*
 <settings>
   <settinghead>
@@ -278,15 +647,20 @@ func HandleSettings(context *Context, dec *xml.Decoder, start xml.StartElement)
 
 	{
 		for _, row := range t.Rows {
-			if !isWebAudience(row.GetAttr("audience"), row.GetAttr("print"), row.GetAttr("deliveryTarget")) {
+			conditions, included := context.ElementConditions(context.Conditions, "setting", row.Attr)
+			if !included {
 				continue
 			}
+			inherited := context.Conditions
+			context.Conditions = conditions
+
 			row.SetAttr("class", "setting")
 			emitStart("div", row.Attr...)
 
-			emitStart("h3", row.Name.Attr...)
+			heading := context.Heading()
+			emitStart(heading, row.Name.Attr...)
 			context.Encoder.WriteRaw(row.Name.Text)
-			emitEnd("h3")
+			emitEnd(heading)
 
 			emitStart("div", xml.Attr{Name: xml.Name{Local: "class"}, Value: "settingbody"})
 
@@ -310,6 +684,8 @@ func HandleSettings(context *Context, dec *xml.Decoder, start xml.StartElement)
 
 			emitEnd("div")
 			emitEnd("div")
+
+			context.Conditions = inherited
 		}
 	}
 
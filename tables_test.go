@@ -0,0 +1,94 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableRowsFiltered(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap":      `<map><topicref href="t.dita"/></map>`,
+		"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+		"t.dita": `<topic id="t"><title>T</title><body>
+			<table><tgroup cols="1"><tbody>
+				<row><entry>kept row</entry></row>
+				<row product="old"><entry>excluded row</entry></row>
+			</tbody></tgroup></table>
+			<table><tgroup cols="1"><tbody product="old">
+				<row><entry>cascaded row</entry></row>
+			</tbody></tgroup></table>
+			<simpletable>
+				<strow><stentry>kept strow</stentry></strow>
+				<strow product="old"><stentry>excluded strow</stentry></strow>
+			</simpletable>
+		</body></topic>`,
+	}, "t.dita")
+
+	output := context.Output.String()
+	for _, kept := range []string{"kept row", "kept strow"} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("missing %q:\n%s", kept, output)
+		}
+	}
+	for _, excluded := range []string{"excluded row", "cascaded row", "excluded strow"} {
+		if strings.Contains(output, excluded) {
+			t.Errorf("contains %q:\n%s", excluded, output)
+		}
+	}
+}
+
+func TestSimpleTableOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		want    []string
+		notwant []string
+	}{
+		{
+			name: "row attributes",
+			table: `<simpletable>
+				<sthead class="- topic/sthead " audience="admin"><stentry>H</stentry></sthead>
+				<strow class="- topic/strow " props="x" outputclass="odd" product="new"><stentry platform="win">C</stentry></strow>
+			</simpletable>`,
+			want:    []string{`<tr>`, `<tr outputclass="odd"`, `<td>C</td>`},
+			notwant: []string{`audience=`, `props=`, `product=`, `platform=`, `topic/`},
+		},
+		{
+			name: "filtered head",
+			table: `<simpletable>
+				<sthead><stentry product="old">H</stentry></sthead>
+				<strow><stentry>C</stentry></strow>
+			</simpletable>`,
+			want:    []string{`<td>C</td>`},
+			notwant: []string{`<thead>`},
+		},
+		{
+			name: "filtered properties head",
+			table: `<properties>
+				<prophead><proptypehd product="old">Type</proptypehd></prophead>
+				<property><proptype>T</proptype></property>
+			</properties>`,
+			want: []string{`<thead>`},
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap":      `<map><topicref href="t.dita"/></map>`,
+			"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+			"t.dita":         `<reference id="t"><title>T</title><refbody>` + test.table + `</refbody></reference>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.name, want, output)
+			}
+		}
+		for _, notwant := range test.notwant {
+			if strings.Contains(output, notwant) {
+				t.Errorf("%s: contains %q:\n%s", test.name, notwant, output)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// TaskLabels contains generated headings for task sections
+var TaskLabels = map[string]string{
+	"prereq":              "Before you begin",
+	"context":             "About this task",
+	"result":              "Results",
+	"postreq":             "What to do next",
+	"tasktroubleshooting": "Troubleshooting",
+}
+
+// LabeledSection creates a processor that outputs element as a div
+// with a generated heading from labels, unless the element has a title.
+// Sections without an id get a generated one.
+func LabeledSection(labels map[string]string) TokenProcessor {
+	return func(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+		kind := context.Rules.RuleName(&start)
+
+		start.Name.Local = "div"
+		setAttr(&start, "class", "section "+kind)
+		if getAttr(&start, "id") == "" {
+			setAttr(&start, "id", context.NewAnchor(kind))
+		}
+		context.check(context.Encoder.Encode(start))
+		defer func() { context.check(context.Encoder.WriteEnd("div")) }()
+
+		// find the first element to check for a title, the label
+		// is written before any content other than whitespace
+		for {
+			token, err := dec.Token()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+
+			switch token := token.(type) {
+			case xml.EndElement:
+				context.writeLabel(labels[kind])
+				return nil
+			case xml.StartElement:
+				if context.Rules.RuleName(&token) != "title" {
+					context.writeLabel(labels[kind])
+				}
+				if err := context.Handle(dec, token); err != nil {
+					return err
+				}
+				return context.Recurse(dec)
+			case xml.CharData:
+				if strings.TrimSpace(string(token)) != "" {
+					context.writeLabel(labels[kind])
+					if err := context.Handle(dec, token); err != nil {
+						return err
+					}
+					return context.Recurse(dec)
+				}
+			}
+
+			if err := context.Handle(dec, token); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (context *Context) writeLabel(label string) {
+	if label == "" {
+		return
+	}
+	heading := context.Heading()
+	context.check(context.Encoder.WriteStart(heading,
+		attr("class", "sectiontitle sectionlabel")))
+	context.check(context.Encoder.WriteRaw(html.EscapeCharData(label)))
+	context.check(context.Encoder.WriteEnd(heading))
+}
+
+// HandleSteps outputs steps as an ordered list, stepsections split the
+// list and the numbering continues after them.
+// For steps-unordered an unordered list is used instead.
+func HandleSteps(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	kind := context.Rules.RuleName(&start)
+	tag := "ol"
+	if kind == "steps-unordered" {
+		tag = "ul"
+	}
+
+	number := 0
+	open, listed := false, false
+
+	openList := func() {
+		list := xml.StartElement{Name: xml.Name{Local: tag}}
+		setAttr(&list, "class", kind)
+		// the id belongs to the first list only
+		if !listed {
+			setAttr(&list, "id", getAttr(&start, "id"))
+		}
+		setAttr(&list, "outputclass", getAttr(&start, "outputclass"))
+		if number > 0 && tag == "ol" {
+			setAttr(&list, "start", strconv.Itoa(number+1))
+		}
+		context.check(context.Encoder.Encode(list))
+		open, listed = true, true
+	}
+	closeList := func() {
+		if open {
+			context.check(context.Encoder.WriteEnd(tag))
+			open = false
+		}
+	}
+	defer closeList()
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if !open && strings.TrimSpace(string(token)) == "" {
+				continue
+			}
+		case xml.StartElement:
+			if context.Rules.RuleName(&token) == "stepsection" {
+				closeList()
+			} else {
+				if !open {
+					openList()
+				}
+				if !context.ShouldSkip(token) {
+					number++
+				}
+			}
+		}
+
+		if err := context.Handle(dec, token); err != nil {
+			return err
+		}
+	}
+}
+
+// HandleStep outputs step or substep as a list item
+func HandleStep(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	kind := context.Rules.RuleName(&start)
+	importance := getAttr(&start, "importance")
+
+	start.Name.Local = "li"
+	setAttr(&start, "class", kind)
+	setAttr(&start, "importance", "")
+
+	context.check(context.Encoder.Encode(start))
+	switch importance {
+	case "optional":
+		context.Encoder.WriteRaw("(Optional) ")
+	case "required":
+		context.Encoder.WriteRaw("(Required) ")
+	}
+	err := context.Recurse(dec)
+	context.check(context.Encoder.Encode(xml.EndElement{Name: start.Name}))
+
+	return err
+}
@@ -0,0 +1,97 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabeledSectionText(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+		"t.dita": `<task id="t"><title>T</title><taskbody>
+			<context>
+				Some text
+			</context>
+		</taskbody></task>`,
+	}, "t.dita")
+
+	output := context.Output.String()
+	label := strings.Index(output, TaskLabels["context"])
+	text := strings.Index(output, "Some text")
+	if label < 0 || text < 0 {
+		t.Fatalf("missing label or text:\n%s", output)
+	}
+	if label > text {
+		t.Errorf("label after text:\n%s", output)
+	}
+}
+
+func TestTaskBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notwant []string
+	}{
+		{
+			name: "stepsection",
+			body: `<steps id="s"><step><cmd>A</cmd></step><stepsection>Section</stepsection><step><cmd>B</cmd></step></steps>`,
+			want: []string{
+				`<ol class="steps" id="s"><li class="step">`,
+				`<div class="stepsection">Section</div>`,
+				`<ol class="steps" start="2"><li class="step">`,
+			},
+		},
+		{
+			name:    "excluded step",
+			body:    `<steps><step><cmd>A</cmd></step><step product="old"><cmd>Old</cmd></step><stepsection>Section</stepsection><step><cmd>B</cmd></step></steps>`,
+			want:    []string{`<ol class="steps" start="2">`},
+			notwant: []string{`Old`},
+		},
+		{
+			name:    "steps-unordered",
+			body:    `<steps-unordered><step><cmd>A</cmd></step><stepsection>Section</stepsection><step><cmd>B</cmd></step></steps-unordered>`,
+			want:    []string{`<ul class="steps-unordered"><li class="step">`},
+			notwant: []string{`start=`},
+		},
+		{
+			name: "importance",
+			body: `<steps><step importance="required"><cmd>A</cmd><substeps><substep importance="optional"><cmd>B</cmd></substep></substeps></step></steps>`,
+			want: []string{
+				`<li class="step">(Required) `,
+				`<ol class="substeps"><li class="substep">(Optional) `,
+			},
+			notwant: []string{`importance=`},
+		},
+		{
+			name: "labeled sections",
+			body: `<prereq>Before</prereq><result>Done</result><postreq>After</postreq>`,
+			want: []string{TaskLabels["prereq"], TaskLabels["result"], TaskLabels["postreq"]},
+		},
+		{
+			name: "choicetable",
+			body: `<steps><step><cmd>A</cmd><choicetable><chrow><choption>X</choption><chdesc>Y</chdesc></chrow></choicetable></step></steps>`,
+			want: []string{`<table class="choicetable">`, `X`, `Y`},
+		},
+	}
+
+	for _, test := range tests {
+		context := convertTopic(t, VFS{
+			"m.ditamap":      `<map><topicref href="t.dita"/></map>`,
+			"filter.ditaval": `<val><prop att="product" val="old" action="exclude"/></val>`,
+			"t.dita":         `<task id="t"><title>T</title><taskbody>` + test.body + `</taskbody></task>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		for _, want := range test.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("%s: missing %q:\n%s", test.name, want, output)
+			}
+		}
+		for _, notwant := range test.notwant {
+			if strings.Contains(output, notwant) {
+				t.Errorf("%s: contains %q:\n%s", test.name, notwant, output)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+
+	"github.com/raintreeinc/ditaconvert/html"
+)
+
+// TroubleshootingLabels contains generated headings for troubleshooting sections
+var TroubleshootingLabels = map[string]string{
+	"condition": "Condition",
+	"cause":     "Cause",
+	"remedy":    "Remedy",
+
+	"responsibleParty": "Responsible party:",
+}
+
+// HandleTroubleSolution outputs troubleSolution as a div with an anchor
+func HandleTroubleSolution(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	start.Name.Local = "div"
+	setAttr(&start, "class", "troubleSolution")
+	if getAttr(&start, "id") == "" {
+		setAttr(&start, "id", context.NewAnchor("troubleSolution"))
+	}
+	return context.EmitWithChildren(dec, start)
+}
+
+// HandleResponsibleParty outputs responsibleParty as a labeled paragraph
+func HandleResponsibleParty(context *Context, dec *xml.Decoder, start xml.StartElement) error {
+	start.Name.Local = "p"
+	setAttr(&start, "class", "responsibleParty")
+
+	context.check(context.Encoder.Encode(start))
+	if label := TroubleshootingLabels["responsibleParty"]; label != "" {
+		context.check(context.Encoder.WriteRaw(`<strong>` + html.EscapeCharData(label) + `</strong> `))
+	}
+	err := context.Recurse(dec)
+	context.check(context.Encoder.WriteEnd("p"))
+	return err
+}
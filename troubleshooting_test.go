@@ -0,0 +1,36 @@
+package ditaconvert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResponsiblePartyLabel(t *testing.T) {
+	defer func(label string) {
+		TroubleshootingLabels["responsibleParty"] = label
+	}(TroubleshootingLabels["responsibleParty"])
+
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"Responsible party:", `<p class="responsibleParty"><strong>Responsible party:</strong> Admin</p>`},
+		{"Owner:", `<p class="responsibleParty"><strong>Owner:</strong> Admin</p>`},
+		{"", `<p class="responsibleParty">Admin</p>`},
+	}
+
+	for _, test := range tests {
+		TroubleshootingLabels["responsibleParty"] = test.label
+		context := convertTopic(t, VFS{
+			"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+			"t.dita": `<troubleshooting id="t"><title>T</title><troublebody>
+				<troubleSolution><remedy><responsibleParty>Admin</responsibleParty></remedy></troubleSolution>
+			</troublebody></troubleshooting>`,
+		}, "t.dita")
+
+		output := context.Output.String()
+		if !strings.Contains(output, test.want) {
+			t.Errorf("%q: missing %q:\n%s", test.label, test.want, output)
+		}
+	}
+}
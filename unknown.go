@@ -0,0 +1,128 @@
+package ditaconvert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+)
+
+// UnknownPolicy specifies how elements without rules are converted
+type UnknownPolicy string
+
+const (
+	// emit the element as is, also used when no policy is specified
+	UnknownAsIs = UnknownPolicy("as-is")
+	// emit as span or div with the original name in data-dita
+	UnknownAsElement = UnknownPolicy("element")
+	// emit only the content
+	UnknownUnwrap = UnknownPolicy("unwrap")
+	// fail the conversion
+	UnknownError = UnknownPolicy("error")
+)
+
+// maximum number of locations kept per unknown element
+const unknownSamples = 5
+
+// UnknownElement contains usage of an element without conversion rules
+type UnknownElement struct {
+	Name    string
+	Count   int
+	Samples []string
+}
+
+// blockBases are base DITA elements which are output as div
+var blockBases = map[string]bool{
+	"section": true, "sectiondiv": true, "bodydiv": true, "example": true,
+	"p": true, "div": true, "note": true, "lq": true, "pre": true, "lines": true,
+	"ul": true, "ol": true, "sl": true, "li": true, "sli": true,
+	"dl": true, "dlentry": true, "dlhead": true, "dd": true, "ddhd": true,
+	"fig": true, "figgroup": true, "itemgroup": true, "table": true, "simpletable": true,
+	"linkinfo": true, "linklist": true, "linkpool": true, "abstract": true,
+}
+
+func (index *Index) addUnknown(name, location string) {
+	unknown, ok := index.Unknown[name]
+	if !ok {
+		unknown = &UnknownElement{Name: name}
+		index.Unknown[name] = unknown
+	}
+	unknown.Count++
+
+	if len(unknown.Samples) < unknownSamples {
+		unknown.Samples = appendUnique(unknown.Samples, location)
+	}
+}
+
+type unknownByCount []*UnknownElement
+
+func (xs unknownByCount) Len() int      { return len(xs) }
+func (xs unknownByCount) Swap(i, j int) { xs[i], xs[j] = xs[j], xs[i] }
+func (xs unknownByCount) Less(i, j int) bool {
+	if xs[i].Count == xs[j].Count {
+		return xs[i].Name < xs[j].Name
+	}
+	return xs[i].Count > xs[j].Count
+}
+
+// UnknownElements returns all unhandled elements, most used first
+func (index *Index) UnknownElements() []*UnknownElement {
+	var elements []*UnknownElement
+	for _, unknown := range index.Unknown {
+		elements = append(elements, unknown)
+	}
+	sort.Sort(unknownByCount(elements))
+	return elements
+}
+
+// IsBlockElement checks whether start should be output as a block
+func IsBlockElement(start *xml.StartElement) bool {
+	class := getAttr(start, "class")
+	if !IsDITAClass(class) {
+		class = DefaultClass[start.Name.Local]
+	}
+
+	ancestry := ClassAncestry(class)
+	if len(ancestry) == 0 {
+		return blockBases[start.Name.Local]
+	}
+	return blockBases[ancestry[len(ancestry)-1]]
+}
+
+// location describes where start is, using its id or the id
+// of the nearest enclosing element, e.g. `topic.dita (inside id="intro")`
+func (context *Context) location(start *xml.StartElement) string {
+	if id := getAttr(start, "id"); id != "" {
+		return fmt.Sprintf("%s (id=%q)", context.DecodingPath, id)
+	}
+	if n := len(context.elementIDs); n > 0 {
+		return fmt.Sprintf("%s (inside id=%q)", context.DecodingPath, context.elementIDs[n-1])
+	}
+	return context.DecodingPath
+}
+
+// HandleUnknown converts elements without any rules according to Rules.Unknown
+func (context *Context) HandleUnknown(dec *xml.Decoder, start xml.StartElement) error {
+	name := start.Name.Local
+	context.Index.addUnknown(name, context.location(&start))
+
+	switch context.Rules.Unknown {
+	case UnknownUnwrap:
+		return context.Recurse(dec)
+	case UnknownError:
+		dec.Skip()
+		return fmt.Errorf("unknown element <%s>", name)
+	case UnknownAsElement:
+		if IsBlockElement(&start) {
+			start.Name.Local = "div"
+		} else {
+			start.Name.Local = "span"
+		}
+		setAttr(&start, "data-dita", name)
+	}
+
+	if IsDITAClass(getAttr(&start, "class")) {
+		setAttr(&start, "class", "")
+	}
+	context.Rules.ModifyAttributes(&start, name)
+	return context.EmitWithChildren(dec, start)
+}
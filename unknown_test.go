@@ -0,0 +1,27 @@
+package ditaconvert
+
+import "testing"
+
+func TestUnknownSampleLocation(t *testing.T) {
+	context := convertTopic(t, VFS{
+		"m.ditamap": `<map><topicref href="t.dita"/></map>`,
+		"t.dita": `<topic id="t"><title>T</title><body>
+			<section id="intro"><p><mystery>x</mystery></p></section>
+			<p><mystery id="own">y</mystery></p>
+		</body></topic>`,
+	}, "t.dita")
+
+	unknown := context.Index.Unknown["mystery"]
+	if unknown == nil {
+		t.Fatal("mystery not reported")
+	}
+	expected := []string{`t.dita (inside id="intro")`, `t.dita (id="own")`}
+	if len(unknown.Samples) != len(expected) {
+		t.Fatalf("got samples %q, expected %q", unknown.Samples, expected)
+	}
+	for i := range expected {
+		if unknown.Samples[i] != expected[i] {
+			t.Errorf("got sample %q, expected %q", unknown.Samples[i], expected[i])
+		}
+	}
+}